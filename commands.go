@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/sascha-andres/godl/godl"
+)
+
+// versionArg returns the version passed as the only argument of fs or the version
+// discovered from .go-version, go.work or go.mod
+func versionArg(fs *flag.FlagSet, logger *slog.Logger) (string, error) {
+	switch fs.NArg() {
+	case 0:
+		version, p, err := godl.DiscoverVersion(".")
+		if err != nil {
+			return "", fmt.Errorf("no version provided and none found: %w", err)
+		}
+		logger.Info("using version from file", "version", version, "file", p)
+		return version, nil
+	case 1:
+		return fs.Arg(0), nil
+	}
+	fs.Usage()
+	return "", errors.New("expected at most one version")
+}
+
+// runInstall implements godl install [version]
+func runInstall(args []string) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl install [flags] [version]\n\n"+
+			"Installs the newest release matching version, read from .go-version, go.work or go.mod if omitted.\n\n")
+		fs.PrintDefaults()
+	}
+	var s settings
+	s.register(fs)
+	destination, linkName := destinationFlags(fs)
+	force := fs.Bool("force", false, "download and install again if the version is already installed")
+	use := fs.Bool("use", false, "link the installed version as -link-name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := s.validate(); err != nil {
+		return err
+	}
+	if *destination == "" {
+		return errors.New("no destination provided")
+	}
+	logger := s.newLogger()
+	version, err := versionArg(fs, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel, err := s.context()
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	var opts []godl.ApplicationOption
+	if *force {
+		opts = append(opts, godl.WithForceDownload())
+	}
+	a, err := s.newApplication(ctx, logger, opts...)
+	if err != nil {
+		return err
+	}
+
+	p, err := a.Install(ctx, version, *destination)
+	if errors.Is(err, godl.ErrAlreadyInstalled) {
+		logger.Info("version already installed", "path", p)
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	if *use {
+		return a.Use(filepath.Base(p), *destination, *linkName)
+	}
+	return nil
+}
+
+// runUse implements godl use [version]
+func runUse(args []string) error {
+	fs := flag.NewFlagSet("use", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl use [flags] [version|-]\n\n"+
+			"Links the newest installed version matching version, read from .go-version, go.work or go.mod if omitted.\n"+
+			"- switches back to the version linked before.\n\n")
+		fs.PrintDefaults()
+	}
+	var s settings
+	destination, linkName := destinationFlags(fs)
+	fs.BoolVar(&s.verbose, "verbose", envBool("GODL_VERBOSE"), "more verbose output")
+	s.registerLockTimeout(fs)
+	force := fs.Bool("force", false, "replace a file or directory at the place of the link")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *destination == "" {
+		return errors.New("no destination provided")
+	}
+	logger := s.newLogger()
+	version, err := versionArg(fs, logger)
+	if err != nil {
+		return err
+	}
+	opts, err := s.lockTimeoutOptions()
+	if err != nil {
+		return err
+	}
+	if *force {
+		opts = append(opts, godl.WithForceLink())
+	}
+	a, err := godl.NewApplication(context.Background(), append(opts, godl.WithLocalOnly(), godl.WithLogger(logger))...)
+	if err != nil {
+		return err
+	}
+	return a.Use(version, *destination, *linkName)
+}
+
+// runLsRemote implements godl ls-remote
+func runLsRemote(args []string) error {
+	fs := flag.NewFlagSet("ls-remote", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl ls-remote [flags]\n\n"+
+			"Lists the releases available for the current platform, installed is set for versions in -destination.\n\n")
+		fs.PrintDefaults()
+	}
+	var s settings
+	s.register(fs)
+	destination, _ := destinationFlags(fs)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if err := s.validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel, err := s.context()
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	a, err := s.newApplication(ctx, s.newLogger())
+	if err != nil {
+		return err
+	}
+	releases, err := remoteReleases(a.Downloads, *destination)
+	if err != nil {
+		return err
+	}
+	return printRemoteReleases(os.Stdout, *output, releases)
+}
+
+// runEnv implements godl env [version]
+func runEnv(args []string) error {
+	fs := flag.NewFlagSet("env", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl env [flags] [version]\n\n"+
+			"Prints the effective configuration and the GOROOT of the linked version. With -shell the\n"+
+			"commands setting GOROOT and PATH for version or the linked version are printed instead,\n"+
+			"e.g. eval \"$(godl env -shell bash)\".\n\n")
+		fs.PrintDefaults()
+	}
+	destination, linkName := destinationFlags(fs)
+	cacheDir := cacheDirFlag(fs)
+	shell := fs.String("shell", "", "print commands for bash, zsh, fish or posix shells")
+	discover := fs.Bool("discover", false, "with -shell use the installed version required by .go-version, go.work or go.mod, unset GOROOT if there is none")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 || (fs.NArg() == 1 && (*shell == "" || *discover)) {
+		fs.Usage()
+		return errors.New("a version is only accepted with -shell and without -discover")
+	}
+
+	if *shell != "" {
+		if err := checkShell(*shell); err != nil {
+			return err
+		}
+		if *destination == "" {
+			return errors.New("no destination provided")
+		}
+		goroot := godl.CreateSymlinkPath(*destination, *linkName)
+		switch {
+		case *discover:
+			var err error
+			goroot, err = discoverGoroot(*destination)
+			if err != nil {
+				return err
+			}
+		case fs.NArg() == 1:
+			v, err := godl.ResolveInstalled(fs.Arg(0), *destination)
+			if err != nil {
+				return err
+			}
+			goroot = filepath.Join(*destination, v.String())
+		}
+		if goroot != "" {
+			var err error
+			if goroot, err = filepath.Abs(goroot); err != nil {
+				return err
+			}
+		}
+		return writeShellEnv(os.Stdout, *shell, goroot)
+	}
+
+	version, versionFile, err := godl.DiscoverVersion(".")
+	if err != nil && !errors.Is(err, godl.ErrNoVersionFile) {
+		return err
+	}
+	if v, ok := os.LookupEnv("GODL_VERSION"); ok {
+		version, versionFile = v, ""
+	}
+	goroot := ""
+	if *destination != "" {
+		goroot = godl.CreateSymlinkPath(*destination, *linkName)
+	}
+
+	for _, kv := range [][2]string{
+		{"GODL_DESTINATION", *destination},
+		{"GODL_LINK_NAME", *linkName},
+		{"GODL_CACHE_DIR", *cacheDir},
+		{"GODL_VERSION", version},
+		{"GODL_VERSION_FILE", versionFile},
+		{"GOROOT", goroot},
+	} {
+		fmt.Printf("%s=%s\n", kv[0], kv[1])
+	}
+	return nil
+}
+
+// runHelp implements godl help
+func runHelp(args []string) error {
+	_, _ = fmt.Fprint(os.Stderr, `usage: godl <command> [flags] [arguments]
+
+Commands:
+    install    download and install a version
+    use        link an installed version
+    ls-remote  list the available releases
+    ls, list   list the installed versions
+    rm, remove remove an installed version
+    prune      remove old installed versions
+    env        print the effective configuration or shell exports (-shell)
+    hook       print a shell hook switching versions per directory
+    cache      manage the archive cache (list, clean, size)
+    serve      serve the archive cache as a mirror for other godl instances
+    help       print this help
+
+Run godl <command> -h for the flags of a command. Without a command the classic
+flags (-download, -link, -print, ...) are used, see godl -h.
+`)
+	return nil
+}
@@ -6,6 +6,9 @@ require (
 	github.com/PuerkitoBio/goquery v1.12.0
 	github.com/google/go-cmp v0.6.0
 	github.com/sascha-andres/reuse v0.16.0
+	golang.org/x/mod v0.40.0
+	golang.org/x/sys v0.47.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,221 @@
+package godl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	stableVersionExtractRegex          = `^go(?P<version>[1-9]\.[0-9]{1,3}(\.[0-9]{1,3})?)\.(?P<goos>[^-]*)-(?P<goarch>[^\\.]*)`
+	inludeReleaseCandidateExtractRegex = `^go(?P<version>[1-9]\.[0-9]{1,3}(\.[0-9]{1,3})?(rc[0-9]{1,2})?)\.(?P<goos>[^-]*)-(?P<goarch>[^\\.]*)`
+)
+
+// NewApplication returns an instance of the application, ctx is used to query
+// the available versions
+func NewApplication(ctx context.Context, opts ...ApplicationOption) (*Application, error) {
+	a := &Application{logger: slog.Default(), httpClient: http.DefaultClient, retries: defaultRetries, goos: runtime.GOOS, goarch: releaseArch(runtime.GOARCH), lockTimeout: DefaultLockTimeout}
+	a.mirrors, _ = newMirrorSet([]string{BaseUrl})
+	a.sumDB, _ = parseSumDB(defaultSumDB)
+	if dir, err := DefaultCacheDir(); err == nil {
+		a.cache = NewCache(dir)
+	}
+	for i := range opts {
+		err := opts[i](a)
+		if err != nil {
+			return nil, fmt.Errorf("error setting option: %w", err)
+		}
+	}
+	var r *regexp.Regexp
+	var err error
+	if a.includeReleaseCandidates {
+		r, err = regexp.Compile(inludeReleaseCandidateExtractRegex)
+	} else {
+		r, err = regexp.Compile(stableVersionExtractRegex)
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.versionRegex = r
+	a.mirrors.logger = a.logger
+	if a.localOnly {
+		return a, nil
+	}
+	return a, a.queryVersions(ctx)
+}
+
+// releaseArch returns the architecture as named by go.dev releases for goarch, 32-bit arm
+// releases are named armv6l
+func releaseArch(goarch string) string {
+	if goarch == "arm" {
+		return "armv6l"
+	}
+	return goarch
+}
+
+// queryVersions connects to go.dev, the configured mirrors or module proxies to gather all
+// known go versions, see queryMirror and queryToolchains. If no mirror is reachable or in offline mode the cached release
+// index is used
+func (a *Application) queryVersions(ctx context.Context) error {
+	a.Downloads = nil
+	if a.offline {
+		if err := a.queryOffline(); err != nil {
+			return err
+		}
+		sort.Sort(ByVersion(a.Downloads))
+		return nil
+	}
+	var err error
+	if a.goProxies != nil {
+		err = a.queryToolchains(ctx)
+	} else {
+		err = a.queryMirrors(ctx)
+	}
+	if err != nil && errors.Is(err, ErrNetwork) && ctx.Err() == nil {
+		a.logger.Warn("network unreachable, falling back to cached release index", "err", err)
+		a.Downloads = nil
+		if indexErr := a.queryCachedIndex(); indexErr != nil {
+			a.logger.Debug("no usable cached release index", "err", indexErr)
+			return err
+		}
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	sort.Sort(ByVersion(a.Downloads))
+	return nil
+}
+
+// queryMirrors gathers all known go versions from the first healthy mirror that answers
+func (a *Application) queryMirrors(ctx context.Context) error {
+	var err error
+	mirrors := a.mirrors.healthy()
+	for i, m := range mirrors {
+		a.Downloads = nil
+		err = a.queryMirror(ctx, m)
+		a.mirrors.report(m, err)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if i+1 < len(mirrors) {
+			a.logger.Warn("error querying mirror, trying next one", "mirror", m.base.String(), "err", err)
+		}
+	}
+	return err
+}
+
+// queryMirror gathers all known go versions from m, the JSON release feed is preferred and
+// the download page is used as a fallback if m answers without a usable feed. Network errors
+// are returned right away, so the next mirror is tried
+func (a *Application) queryMirror(ctx context.Context, m *mirror) error {
+	err := a.queryFeed(ctx, m)
+	if err == nil || isTransient(err) || ctx.Err() != nil {
+		return err
+	}
+	a.logger.Warn("error querying release feed, falling back to download page", "err", err)
+	a.Downloads = nil
+	return a.queryDownloadPage(ctx, m)
+}
+
+// queryDownloadPage scrapes the download page of m to gather all known go versions
+func (a *Application) queryDownloadPage(ctx context.Context, m *mirror) error {
+	res, err := httpGet(ctx, a.httpClient, m.base.String(), 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := res.Body.Close()
+		if err != nil {
+			a.logger.Warn("error closing http response body", "err", err)
+		}
+	}()
+
+	// Load the HTML document
+	doc, err := goquery.NewDocumentFromReader(res.Body)
+	if err != nil {
+		return fmt.Errorf("error parsing download page: %w", err)
+	}
+
+	// Find the review items
+	doc.Find(".download").Each(func(i int, s *goquery.Selection) {
+		a.processSelection(m, s)
+	})
+	return nil
+}
+
+// ByVersion sorts downloads from the newest to the oldest version
+type ByVersion []Download
+
+func (a ByVersion) Len() int           { return len(a) }
+func (a ByVersion) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a ByVersion) Less(i, j int) bool { return a[i].Version.Compare(a[j].Version) > 0 }
+
+// processSelection is transforming a download link to out internal version representation
+// it will skip over go versions that are not for the selected OS or arch
+func (a *Application) processSelection(m *mirror, s *goquery.Selection) {
+	title := s.Text()
+	if !strings.HasSuffix(title, ".zip") && !strings.HasSuffix(title, ".tar.gz") {
+		return
+	}
+	if !a.versionRegex.Match([]byte(title)) {
+		return
+	}
+	if _, exists := s.Attr("href"); !exists {
+		return
+	}
+
+	match := a.versionRegex.FindStringSubmatch(title)
+	result := make(map[string]string)
+	for i, name := range a.versionRegex.SubexpNames() {
+		if i != 0 && name != "" {
+			result[name] = match[i]
+		}
+	}
+	v, err := Parse(result["version"])
+	if err != nil {
+		return
+	}
+
+	if result["goos"] != a.goos || result["goarch"] != a.goarch {
+		return
+	}
+
+	d := Download{
+		Url:         m.base.JoinPath(title),
+		Version:     v,
+		GoOs:        result["goos"],
+		GoArch:      result["goarch"],
+		FileName:    title,
+		Kind:        "archive",
+		Sha256:      strings.TrimSpace(s.Closest("tr").Find("tt").First().Text()),
+		Logger:      a.logger,
+		Client:      a.httpClient,
+		Retries:     a.retries,
+		Progress:    a.progress,
+		Connections: a.connections,
+		mirrors:     a.mirrors,
+	}
+
+	a.Downloads = append(a.Downloads, d)
+}
+
+// GetDownload will return download data for the newest version matching the version
+// constraint, see ParseConstraint. Versions are only queried again if none are known yet
+func (a *Application) GetDownload(ctx context.Context, version string) (*Download, error) {
+	if len(a.Downloads) == 0 {
+		err := a.queryVersions(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a.Resolve(version)
+}
@@ -0,0 +1,208 @@
+package godl
+
+import (
+	"cmp"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+func WithIncludeReleaseCandidates() ApplicationOption {
+	return func(application *Application) error {
+		application.includeReleaseCandidates = true
+		return nil
+	}
+}
+
+// WithBaseUrl allows overriding the base url
+func WithBaseUrl(baseUrl string) ApplicationOption {
+	return WithMirrors(baseUrl)
+}
+
+// WithMirrors sets the base urls of the download page, e.g. https://go.dev/dl/ or
+// https://golang.google.cn/dl/. Mirrors are tried in order for the release index and the
+// archives, a mirror failing with a network error is skipped for the rest of the run
+func WithMirrors(baseUrls ...string) ApplicationOption {
+	return func(application *Application) error {
+		mirrors, err := newMirrorSet(baseUrls)
+		if err != nil {
+			return err
+		}
+		application.mirrors = mirrors
+		return nil
+	}
+}
+
+// WithForceDownload replaces an existing installation instead of failing with ErrAlreadyInstalled
+func WithForceDownload() ApplicationOption {
+	return func(application *Application) error {
+		application.forceDownload = true
+		return nil
+	}
+}
+
+func WithVerbose() ApplicationOption {
+	return func(application *Application) error {
+		application.verbose = true
+		return nil
+	}
+}
+
+// WithLogger allows setting the Logger
+func WithLogger(logger *slog.Logger) ApplicationOption {
+	return func(application *Application) error {
+		application.logger = logger
+		return nil
+	}
+}
+
+// WithHTTPClient allows setting the http client used for all requests, e.g. to
+// configure timeouts, a proxy or custom certificate authorities
+func WithHTTPClient(client *http.Client) ApplicationOption {
+	return func(application *Application) error {
+		if client == nil {
+			return errors.New("no http client provided")
+		}
+		application.httpClient = client
+		return nil
+	}
+}
+
+// WithRetries sets the number of times a download failing with a transient error
+// (connection problems, 5xx responses) is retried using exponential backoff
+func WithRetries(retries int) ApplicationOption {
+	return func(application *Application) error {
+		if retries < 0 {
+			return errors.New("retries must not be negative")
+		}
+		application.retries = retries
+		return nil
+	}
+}
+
+// WithCacheDir sets the directory downloaded archives are cached in, an empty
+// dir disables the cache
+func WithCacheDir(dir string) ApplicationOption {
+	return func(application *Application) error {
+		if dir == "" {
+			application.cache = nil
+			return nil
+		}
+		application.cache = NewCache(dir)
+		return nil
+	}
+}
+
+// WithOffline uses the cached release index and the archive cache instead of the network
+func WithOffline() ApplicationOption {
+	return func(application *Application) error {
+		application.offline = true
+		return nil
+	}
+}
+
+// WithLocalOnly skips querying the available releases on construction, for working with
+// installed versions only. Releases are queried when first needed, e.g. by Install
+func WithLocalOnly() ApplicationOption {
+	return func(application *Application) error {
+		application.localOnly = true
+		return nil
+	}
+}
+
+// WithPlatform selects downloads for another operating system and architecture than the
+// running one, using the names of GOOS and GOARCH (e.g. linux and arm64, windows and amd64).
+// 32-bit arm may be given as arm or armv6l as named by go.dev
+func WithPlatform(goos, goarch string) ApplicationOption {
+	return func(application *Application) error {
+		if goos == "" || goarch == "" {
+			return errors.New("operating system and architecture are required")
+		}
+		application.goos = goos
+		application.goarch = releaseArch(goarch)
+		return nil
+	}
+}
+
+// WithLockTimeout sets the time to wait for another process installing into or linking in the
+// same destination, ErrLocked is returned afterwards. A timeout of 0 fails immediately
+func WithLockTimeout(timeout time.Duration) ApplicationOption {
+	return func(application *Application) error {
+		if timeout < 0 {
+			return errors.New("lock timeout must not be negative")
+		}
+		application.lockTimeout = timeout
+		return nil
+	}
+}
+
+// WithForceLink makes Use replace a file or directory at the place of the link instead of
+// failing with ErrLinkExists
+func WithForceLink() ApplicationOption {
+	return func(application *Application) error {
+		application.forceLink = true
+		return nil
+	}
+}
+
+// WithGoProxy fetches toolchains as golang.org/toolchain modules from a GOPROXY style list
+// (e.g. https://proxy.golang.org,direct) instead of go.dev. Entries are tried like the go
+// command does, direct uses go.dev and off disables downloads. Modules are verified using
+// the checksum database, see WithGoSumDB and WithSumFile
+func WithGoProxy(goproxy string) ApplicationOption {
+	return func(application *Application) error {
+		proxies, err := parseGoProxy(goproxy)
+		if err != nil {
+			return err
+		}
+		application.goProxies = proxies
+		return nil
+	}
+}
+
+// WithGoSumDB configures the checksum database verifying toolchain modules like GOSUMDB and
+// GONOSUMDB do, sum.golang.org is used by default. If the database is off or not used for
+// golang.org/toolchain the checksum has to be listed in the sum file
+func WithGoSumDB(gosumdb, gonosumdb string) ApplicationOption {
+	return func(application *Application) error {
+		db, err := parseSumDB(cmp.Or(gosumdb, defaultSumDB))
+		if err != nil {
+			return err
+		}
+		application.sumDB = db
+		application.noSumDB = gonosumdb
+		return nil
+	}
+}
+
+// WithSumFile uses the go.sum style file to verify toolchain modules, listed checksums take
+// precedence over the checksum database, e.g. for installing offline from a file:// proxy
+func WithSumFile(file string) ApplicationOption {
+	return func(application *Application) error {
+		application.sumFile = file
+		return nil
+	}
+}
+
+// WithConnections downloads archives of at least 16 MiB using n concurrent range requests if
+// the server supports them. Without this option or with n set to 1 a single request is used,
+// the command line tool defaults to 4
+func WithConnections(n int) ApplicationOption {
+	return func(application *Application) error {
+		if n < 1 {
+			return errors.New("connections must be at least 1")
+		}
+		application.connections = n
+		return nil
+	}
+}
+
+// WithProgress calls fn with the progress of downloads and extractions, e.g. to render a
+// progress bar. Reports of a phase are at least 100ms apart, the last one has Done set
+func WithProgress(fn ProgressFunc) ApplicationOption {
+	return func(application *Application) error {
+		application.progress = fn
+		return nil
+	}
+}
@@ -0,0 +1,122 @@
+package godl
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testCasesByVersion = []struct {
+	name     string
+	dlds     []Download
+	expected []Download
+}{
+	{
+		name: "rc",
+		dlds: []Download{
+			{
+				Version: mustParse("1.19.1rc1"),
+			},
+			{
+				Version: mustParse("1.19.1"),
+			},
+			{
+				Version: mustParse("1.20rc3"),
+			},
+			{
+				Version: mustParse("1.3"),
+			},
+		},
+		expected: []Download{
+			{
+				Version: mustParse("1.20rc3"),
+			},
+			{
+				Version: mustParse("1.19.1"),
+			},
+			{
+				Version: mustParse("1.19.1rc1"),
+			},
+			{
+				Version: mustParse("1.3"),
+			},
+		},
+	},
+	{
+		name: "patch-zero",
+		dlds: []Download{
+			{
+				Version: mustParse("1.21rc2"),
+			},
+			{
+				Version: mustParse("1.21.0"),
+			},
+			{
+				Version: mustParse("1.21beta1"),
+			},
+			{
+				Version: mustParse("1.20.14"),
+			},
+			{
+				Version: mustParse("1.21.1"),
+			},
+		},
+		expected: []Download{
+			{
+				Version: mustParse("1.21.1"),
+			},
+			{
+				Version: mustParse("1.21.0"),
+			},
+			{
+				Version: mustParse("1.21rc2"),
+			},
+			{
+				Version: mustParse("1.21beta1"),
+			},
+			{
+				Version: mustParse("1.20.14"),
+			},
+		},
+	},
+	{
+		name: "two-digit",
+		dlds: []Download{
+			{
+				Version: mustParse("1.9.7"),
+			},
+			{
+				Version: mustParse("10.1.0"),
+			},
+			{
+				Version: mustParse("1.10"),
+			},
+		},
+		expected: []Download{
+			{
+				Version: mustParse("10.1.0"),
+			},
+			{
+				Version: mustParse("1.10"),
+			},
+			{
+				Version: mustParse("1.9.7"),
+			},
+		},
+	},
+}
+
+func TestByVersion(t *testing.T) {
+	for i := range testCasesByVersion {
+		i := i
+		t.Run(testCasesByVersion[i].name, func(t *testing.T) {
+			sort.Sort(ByVersion(testCasesByVersion[i].dlds))
+			diff := cmp.Diff(testCasesByVersion[i].dlds, testCasesByVersion[i].expected, cmp.AllowUnexported(Download{}))
+			if diff != "" {
+				t.Logf("mismatch in expectation: \n\n%s", diff)
+				t.Fail()
+			}
+		})
+	}
+}
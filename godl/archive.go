@@ -0,0 +1,399 @@
+package godl
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// withinRoot joins name onto root and ensures the result does not escape root
+func withinRoot(root, name string) (string, error) {
+	target := filepath.Join(root, name)
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("%w: invalid file path %q: outside of %s", ErrArchiveCorrupt, name, root)
+	}
+	return target, nil
+}
+
+// noSymlinkIn ensures no directory from root down to dir is a symbolic link, so an entry is
+// never extracted through a link extracted earlier, which may point outside of root
+func noSymlinkIn(root, dir string) error {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return err
+	}
+	if rel == "." {
+		return nil
+	}
+	p := root
+	for _, name := range strings.Split(rel, string(os.PathSeparator)) {
+		p = filepath.Join(p, name)
+		fi, err := os.Lstat(p)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: invalid file path %q: below symbolic link", ErrArchiveCorrupt, rel)
+		}
+	}
+	return nil
+}
+
+// maxLinkHops limits the symbolic links followed resolving the target of a link
+const maxLinkHops = 40
+
+// resolveLink returns the path the symbolic link target linkname in dir points to, following
+// the links already extracted below root, and ensures no step leaves root. ".." is only
+// resolved from a directory already extracted, as an entry extracted later could otherwise
+// change where it leads
+func resolveLink(root, dir, linkname string, hops int) (string, error) {
+	if hops > maxLinkHops {
+		return "", fmt.Errorf("%w: too many levels of symbolic links", ErrArchiveCorrupt)
+	}
+	p := dir
+	for _, name := range strings.Split(filepath.FromSlash(linkname), string(os.PathSeparator)) {
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if fi, err := os.Stat(p); err != nil || !fi.IsDir() {
+				return "", fmt.Errorf("%w: invalid link target %q: %s is not an extracted directory", ErrArchiveCorrupt, linkname, p)
+			}
+			p = filepath.Dir(p)
+		default:
+			p = filepath.Join(p, name)
+			fi, err := os.Lstat(p)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
+			if err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				next, err := os.Readlink(p)
+				if err != nil {
+					return "", err
+				}
+				if filepath.IsAbs(next) {
+					return "", fmt.Errorf("%w: invalid link target %q: absolute target %q", ErrArchiveCorrupt, linkname, next)
+				}
+				if p, err = resolveLink(root, filepath.Dir(p), next, hops+1); err != nil {
+					return "", err
+				}
+			}
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return "", err
+		}
+		if _, err := withinRoot(root, rel); err != nil {
+			return "", fmt.Errorf("invalid link target %q: %w", linkname, err)
+		}
+	}
+	return p, nil
+}
+
+// removeIfNotDir removes an existing entry at target unless it is a directory, so
+// extracted files never write through a previously extracted symbolic link. Replacing a
+// symbolic link is rejected, as links extracted before may have been resolved through it
+func removeIfNotDir(target string) error {
+	fi, err := os.Lstat(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return nil
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%w: invalid file path %q: replaces symbolic link", ErrArchiveCorrupt, target)
+	}
+	return os.Remove(target)
+}
+
+// Untar takes a destination path and a reader; a tar reader loops over the tarfile
+// creating the file structure at 'dst' along the way, and writing any files
+// entries escaping 'dst', links pointing outside of 'dst' and special files are rejected
+// extraction stops when ctx is done
+func (a *Application) Untar(ctx context.Context, dst string, r io.Reader) error {
+	root, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+
+	progress := a.tarProgress(r)
+	if progress != nil {
+		r = progressReader{r: r, pr: progress}
+	}
+
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrArchiveCorrupt, err)
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+
+	// directory modes and times are restored last, as extracting files changes them
+	var dirs []*tar.Header
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		header, err := tr.Next()
+
+		switch {
+
+		// if no more files are found restore directories and return
+		case err == io.EOF:
+			for i := len(dirs) - 1; i >= 0; i-- {
+				target, _ := withinRoot(root, dirs[i].Name)
+				if err := os.Chmod(target, dirs[i].FileInfo().Mode().Perm()); err != nil {
+					return err
+				}
+				if err := os.Chtimes(target, time.Time{}, dirs[i].ModTime); err != nil {
+					return err
+				}
+			}
+			progress.done()
+			return nil
+
+		// return any other error
+		case err != nil:
+			return fmt.Errorf("%w: %w", ErrArchiveCorrupt, err)
+
+		// if the header is nil, just skip it (not sure how this happens)
+		case header == nil:
+			continue
+		}
+
+		target, err := withinRoot(root, header.Name)
+		if err != nil {
+			return err
+		}
+
+		if a.verbose {
+			a.logger.Debug("tar content", "path", target)
+		}
+
+		// check the file type
+		switch header.Typeflag {
+
+		// if it's a dir, and it doesn't exist, create it
+		case tar.TypeDir:
+			if err := noSymlinkIn(root, target); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, header)
+			continue
+
+		// if it's a file create it
+		case tar.TypeReg:
+			if err := noSymlinkIn(root, filepath.Dir(target)); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := removeIfNotDir(target); err != nil {
+				return err
+			}
+			mode := header.FileInfo().Mode().Perm()
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+
+			// copy over contents
+			if _, err := io.Copy(f, tr); err != nil {
+				_ = f.Close()
+				return fmt.Errorf("error extracting %s: %w", header.Name, err)
+			}
+
+			// manually close here after each file operation; defering would cause each file close
+			// to wait until all operations have completed.
+			if err := f.Close(); err != nil {
+				return err
+			}
+			// the mode passed to OpenFile is subject to umask
+			if err := os.Chmod(target, mode); err != nil {
+				return err
+			}
+			if err := os.Chtimes(target, time.Time{}, header.ModTime); err != nil {
+				return err
+			}
+
+		// a symbolic link must not point outside of the tree
+		case tar.TypeSymlink:
+			if filepath.IsAbs(header.Linkname) {
+				return fmt.Errorf("%w: invalid symbolic link %q: absolute target %q", ErrArchiveCorrupt, header.Name, header.Linkname)
+			}
+			if err := noSymlinkIn(root, filepath.Dir(target)); err != nil {
+				return fmt.Errorf("invalid symbolic link %q: %w", header.Name, err)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			// the target is resolved against the extracted tree, so links cannot be chained
+			// to leave it
+			if _, err := resolveLink(root, filepath.Dir(target), header.Linkname, 0); err != nil {
+				return fmt.Errorf("invalid symbolic link %q: %w", header.Name, err)
+			}
+			if err := removeIfNotDir(target); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+
+		// a hard link must reference a regular file already extracted to the tree
+		case tar.TypeLink:
+			source, err := withinRoot(root, header.Linkname)
+			if err != nil {
+				return fmt.Errorf("invalid hard link %q: %w", header.Name, err)
+			}
+			if err := noSymlinkIn(root, filepath.Dir(source)); err != nil {
+				return fmt.Errorf("invalid hard link %q: %w", header.Name, err)
+			}
+			if err := noSymlinkIn(root, filepath.Dir(target)); err != nil {
+				return fmt.Errorf("invalid hard link %q: %w", header.Name, err)
+			}
+			fi, err := os.Lstat(source)
+			if err != nil {
+				return fmt.Errorf("%w: invalid hard link %q: %w", ErrArchiveCorrupt, header.Name, err)
+			}
+			if !fi.Mode().IsRegular() {
+				return fmt.Errorf("%w: invalid hard link %q: %q is not a regular file", ErrArchiveCorrupt, header.Name, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := removeIfNotDir(target); err != nil {
+				return err
+			}
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+
+		// extended headers carry no content of their own
+		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+			continue
+
+		default:
+			return fmt.Errorf("%w: unsupported tar entry %q of type %q", ErrArchiveCorrupt, header.Name, header.Typeflag)
+		}
+		progress.addFile(0)
+	}
+}
+
+// tarProgress returns the progress reporter for extracting r, the archive size is known if
+// r is a file
+func (a *Application) tarProgress(r io.Reader) *progressReporter {
+	var fileName string
+	var total int64
+	if f, ok := r.(*os.File); ok {
+		fileName = filepath.Base(f.Name())
+		if fi, err := f.Stat(); err == nil {
+			total = fi.Size()
+		}
+	}
+	return newProgressReporter(a.progress, PhaseExtract, fileName, total)
+}
+
+// zipProgress returns the progress reporter for extracting archive
+func (a *Application) zipProgress(archive *zip.ReadCloser, zipFile string) *progressReporter {
+	var total int64
+	for _, f := range archive.File {
+		total += int64(f.CompressedSize64)
+	}
+	return newProgressReporter(a.progress, PhaseExtract, filepath.Base(zipFile), total)
+}
+
+// Unzip takes a destination path and a file and extracts it, extraction stops
+// when ctx is done
+func (a *Application) Unzip(ctx context.Context, zipFile, dst string) error {
+	archive, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrArchiveCorrupt, err)
+	}
+	defer func() {
+		err := archive.Close()
+		if err != nil {
+			a.logger.Warn("error closing zip archive", "err", err)
+		}
+	}()
+
+	progress := a.zipProgress(archive, zipFile)
+	for _, f := range archive.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		filePath, err := withinRoot(filepath.Clean(dst), f.Name)
+		if err != nil {
+			return err
+		}
+		a.logger.Debug("extracting file", "path", filePath)
+
+		if f.FileInfo().IsDir() {
+			a.logger.Debug("creating directory", "path", filePath)
+			if err := os.MkdirAll(filePath, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			return err
+		}
+
+		if err := a.extractZipFile(f, filePath); err != nil {
+			return err
+		}
+		progress.addFile(int64(f.CompressedSize64))
+	}
+	progress.done()
+	return nil
+}
+
+// extractZipFile writes a single file from a zip archive to filePath
+func (a *Application) extractZipFile(f *zip.File, filePath string) error {
+	fileInArchive, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrArchiveCorrupt, err)
+	}
+	defer func() {
+		err := fileInArchive.Close()
+		if err != nil {
+			a.logger.Warn("error closing in archive file", "err", err)
+		}
+	}()
+
+	dstFile, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dstFile, fileInArchive); err != nil {
+		_ = dstFile.Close()
+		return fmt.Errorf("error extracting %s: %w", f.Name, err)
+	}
+
+	return dstFile.Close()
+}
@@ -0,0 +1,193 @@
+package godl
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// buildTarGz returns a gzipped tar archive containing the given entries
+func buildTarGz(t *testing.T, entries []tar.Header, contents map[string]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for i := range entries {
+		h := entries[i]
+		h.Size = int64(len(contents[h.Name]))
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatalf("error writing header: %s", err)
+		}
+		if _, err := tw.Write([]byte(contents[h.Name])); err != nil {
+			t.Fatalf("error writing content: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("error closing tar writer: %s", err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatalf("error closing gzip writer: %s", err)
+	}
+	return &buf
+}
+
+var testCasesUntarRejected = []struct {
+	name    string
+	entries []tar.Header
+}{
+	{
+		name:    "traversal",
+		entries: []tar.Header{{Name: "go/../../evil", Typeflag: tar.TypeReg, Mode: 0644}},
+	},
+	{
+		name:    "absolute-symlink",
+		entries: []tar.Header{{Name: "go/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}},
+	},
+	{
+		name:    "escaping-symlink",
+		entries: []tar.Header{{Name: "go/bin/link", Typeflag: tar.TypeSymlink, Linkname: "../../../evil"}},
+	},
+	{
+		name:    "escaping-hardlink",
+		entries: []tar.Header{{Name: "go/link", Typeflag: tar.TypeLink, Linkname: "../evil"}},
+	},
+	{
+		name: "chained-symlinks",
+		entries: []tar.Header{
+			{Name: "go/s", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "go/s/t", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "go/s/s/t2", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+			{Name: "go/t2/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+	},
+	{
+		name: "chained-link-target",
+		entries: []tar.Header{
+			{Name: "go/b", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "go/esc", Typeflag: tar.TypeSymlink, Linkname: "b/c/../../.."},
+		},
+	},
+	{
+		name: "link-target-before-link",
+		entries: []tar.Header{
+			{Name: "go/esc", Typeflag: tar.TypeSymlink, Linkname: "b/c/../../.."},
+			{Name: "go/b", Typeflag: tar.TypeSymlink, Linkname: "."},
+		},
+	},
+	{
+		name: "replaced-symlink",
+		entries: []tar.Header{
+			{Name: "go/sub/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "go/b", Typeflag: tar.TypeSymlink, Linkname: "sub"},
+			{Name: "go/esc", Typeflag: tar.TypeSymlink, Linkname: "b/.."},
+			{Name: "go/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+		},
+	},
+	{
+		name: "file-below-symlink",
+		entries: []tar.Header{
+			{Name: "go/up", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "go/up/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+	},
+	{
+		name: "directory-symlink",
+		entries: []tar.Header{
+			{Name: "go/d", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: "go/d/", Typeflag: tar.TypeDir, Mode: 0777},
+		},
+	},
+	{
+		name: "hardlink-below-symlink",
+		entries: []tar.Header{
+			{Name: "go/VERSION", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "go/s", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			{Name: "go/link", Typeflag: tar.TypeLink, Linkname: "go/s/go/VERSION"},
+		},
+	},
+	{
+		name:    "fifo",
+		entries: []tar.Header{{Name: "go/fifo", Typeflag: tar.TypeFifo, Mode: 0644}},
+	},
+	{
+		name:    "device",
+		entries: []tar.Header{{Name: "go/dev", Typeflag: tar.TypeChar, Mode: 0644}},
+	},
+}
+
+func TestUntarRejected(t *testing.T) {
+	a := &Application{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	for _, tc := range testCasesUntarRejected {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "dst")
+			if err := os.Mkdir(dst, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := a.Untar(context.Background(), dst, buildTarGz(t, tc.entries, nil)); !errors.Is(err, ErrArchiveCorrupt) {
+				t.Fatalf("expected ErrArchiveCorrupt, got %v", err)
+			}
+			if _, err := os.Lstat(filepath.Join(filepath.Dir(dst), "evil")); err == nil {
+				t.Error("file outside of destination was created")
+			}
+		})
+	}
+}
+
+func TestUntar(t *testing.T) {
+	a := &Application{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	modTime := time.Date(2024, 2, 6, 12, 0, 0, 0, time.UTC)
+	entries := []tar.Header{
+		{Name: "go/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime},
+		{Name: "go/bin/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime},
+		{Name: "go/bin/go", Typeflag: tar.TypeReg, Mode: 0755, ModTime: modTime},
+		{Name: "go/VERSION", Typeflag: tar.TypeReg, Mode: 0644, ModTime: modTime},
+		{Name: "go/bin/gofmt", Typeflag: tar.TypeLink, Linkname: "go/bin/go"},
+		{Name: "go/bin/version", Typeflag: tar.TypeSymlink, Linkname: "../VERSION"},
+	}
+	contents := map[string]string{"go/bin/go": "binary", "go/VERSION": "go1.22.1"}
+	dst := t.TempDir()
+
+	// a stale file must be truncated
+	if err := os.MkdirAll(filepath.Join(dst, "go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dst, "go", "VERSION"), []byte("a much longer stale content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Untar(context.Background(), dst, buildTarGz(t, entries, contents)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "go", "bin", "version"))
+	if err != nil {
+		t.Fatalf("error reading through symlink: %s", err)
+	}
+	if string(data) != "go1.22.1" {
+		t.Errorf("expected go1.22.1, got %q", data)
+	}
+	fi, err := os.Stat(filepath.Join(dst, "go", "bin", "gofmt"))
+	if err != nil {
+		t.Fatalf("error reading hard link: %s", err)
+	}
+	if fi.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755, got %s", fi.Mode().Perm())
+	}
+	if !fi.ModTime().Equal(modTime) {
+		t.Errorf("expected modification time %s, got %s", modTime, fi.ModTime())
+	}
+	fi, err = os.Stat(filepath.Join(dst, "go", "bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(modTime) {
+		t.Errorf("expected directory modification time %s, got %s", modTime, fi.ModTime())
+	}
+}
@@ -0,0 +1,227 @@
+package godl
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	// Cache is a content addressed store of downloaded archives, archives are
+	// stored as <dir>/archives/<sha256>/<file name>
+	Cache struct {
+		// dir is the root directory of the cache
+		dir string
+	}
+
+	// CacheEntry is a single archive in the cache
+	CacheEntry struct {
+		// Sha256 of archive
+		Sha256 string
+		// FileName of archive
+		FileName string
+		// Path of archive
+		Path string
+		// Size of archive in bytes
+		Size int64
+		// ModTime is the time the archive was added to the cache
+		ModTime time.Time
+	}
+)
+
+// DefaultCacheDir returns the default cache directory, godl within the user cache
+// directory ($XDG_CACHE_HOME or ~/.cache on Linux)
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "godl"), nil
+}
+
+// NewCache returns a cache rooted at dir
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the root directory of the cache
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// archivesDir returns the directory holding all archives
+func (c *Cache) archivesDir() string {
+	return filepath.Join(c.dir, "archives")
+}
+
+// Path returns the location of the archive of d within the cache
+func (c *Cache) Path(d *Download) (string, error) {
+	sum, err := hex.DecodeString(d.Sha256)
+	if err != nil || len(sum) != 32 {
+		return "", fmt.Errorf("%w: invalid sha256 %q for %s", ErrChecksumMismatch, d.Sha256, d.FileName)
+	}
+	if d.FileName != filepath.Base(d.FileName) {
+		return "", fmt.Errorf("invalid file name %q", d.FileName)
+	}
+	return filepath.Join(c.archivesDir(), hex.EncodeToString(sum), d.FileName), nil
+}
+
+// Lookup returns the path of the archive of d if it is in the cache and matches its
+// checksum. A corrupt archive is removed from the cache
+func (c *Cache) Lookup(d *Download) (string, bool) {
+	p, err := c.Path(d)
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(p); err != nil {
+		return "", false
+	}
+	sum, err := fileSha256(p)
+	if err == nil {
+		err = d.verify(sum)
+	}
+	if err != nil {
+		d.Logger.Warn("removing corrupt archive from cache", "err", err, "path", p)
+		_ = os.Remove(p)
+		return "", false
+	}
+	return p, true
+}
+
+// List returns all complete archives in the cache
+func (c *Cache) List() ([]CacheEntry, error) {
+	var result []CacheEntry
+	sums, err := os.ReadDir(c.archivesDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, sum := range sums {
+		if !sum.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(c.archivesDir(), sum.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if f.IsDir() || strings.HasSuffix(f.Name(), partialSuffix) || strings.HasSuffix(f.Name(), lockSuffix) {
+				continue
+			}
+			fi, err := f.Info()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, CacheEntry{
+				Sha256:   sum.Name(),
+				FileName: f.Name(),
+				Path:     filepath.Join(c.archivesDir(), sum.Name(), f.Name()),
+				Size:     fi.Size(),
+				ModTime:  fi.ModTime(),
+			})
+		}
+	}
+	return result, nil
+}
+
+// Size returns the number of bytes used by the cache, including partial downloads
+func (c *Cache) Size() (int64, error) {
+	return dirSize(c.dir)
+}
+
+// Clean removes all archives from the cache. The lock of each archive is taken first, waiting
+// up to lockTimeout for processes downloading or extracting it
+func (c *Cache) Clean(ctx context.Context, lockTimeout time.Duration) error {
+	sums, err := os.ReadDir(c.archivesDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, sum := range sums {
+		dir := filepath.Join(c.archivesDir(), sum.Name())
+		if err := c.cleanEntry(ctx, dir, lockTimeout); err != nil {
+			return err
+		}
+		// fails if another process started to download meanwhile
+		_ = os.Remove(dir)
+	}
+	_ = os.Remove(c.archivesDir())
+	return nil
+}
+
+// cleanEntry removes dir holding the archives of a single checksum while their locks are held
+func (c *Cache) cleanEntry(ctx context.Context, dir string, lockTimeout time.Duration) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		// not a directory of the cache
+		return os.RemoveAll(dir)
+	}
+	archives := make(map[string]bool)
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), lockSuffix)
+		for _, kind := range []string{"chunks", "ranges"} {
+			name = strings.TrimSuffix(name, "."+kind+partialSuffix)
+		}
+		archives[strings.TrimSuffix(name, partialSuffix)] = true
+	}
+	var locks []*fileLock
+	defer func() {
+		for _, l := range locks {
+			if err := l.Remove(); err != nil {
+				slog.Warn("error releasing lock", "err", err, "lock", l.f.Name())
+			}
+		}
+	}()
+	for _, name := range slices.Sorted(maps.Keys(archives)) {
+		l, err := waitForLock(ctx, filepath.Join(dir, name)+lockSuffix, lockTimeout, slog.Default())
+		if err != nil {
+			return err
+		}
+		locks = append(locks, l)
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), lockSuffix) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, f.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dirSize returns the size of all files below dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += fi.Size()
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return size, err
+}
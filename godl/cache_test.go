@@ -0,0 +1,157 @@
+package godl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	var archiveRequests int
+	srv := newInstallTestServer(t, &archiveRequests)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cacheDir := t.TempDir()
+
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := a.Install(context.Background(), "1.22.1", t.TempDir()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if archiveRequests != 1 {
+		t.Errorf("expected a single download, got %d", archiveRequests)
+	}
+
+	c := NewCache(cacheDir)
+	entries, err := c.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(entries) != 1 || entries[0].FileName != a.Downloads[0].FileName || entries[0].Sha256 != a.Downloads[0].Sha256 {
+		t.Fatalf("unexpected cache entries %+v", entries)
+	}
+	size, err := c.Size()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if size < entries[0].Size {
+		t.Errorf("expected size of at least %d, got %d", entries[0].Size, size)
+	}
+
+	// a corrupt archive is discarded and downloaded again
+	if err := os.WriteFile(entries[0].Path, []byte("corrupt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", t.TempDir()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if archiveRequests != 2 {
+		t.Errorf("expected corrupt archive to be downloaded again, got %d downloads", archiveRequests)
+	}
+
+	if err := c.Clean(context.Background(), 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "archives")); !os.IsNotExist(err) {
+		t.Errorf("expected archives to be removed")
+	}
+}
+
+func TestOfflineIndexes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	archive, sum := buildToolchainZip(t, testToolchainVersion)
+	toolchains, vkey := newToolchainTestServer(t, archive, sum, nil)
+	feed := newInstallTestServer(t, nil)
+	cacheDir := t.TempDir()
+	feedOpts := []ApplicationOption{WithLogger(logger), WithBaseUrl(feed.URL + "/dl/"), WithCacheDir(cacheDir)}
+	proxyOpts := []ApplicationOption{WithLogger(logger), WithGoProxy(toolchains.URL), WithGoSumDB(vkey, ""), WithCacheDir(cacheDir)}
+
+	// both indexes are cached without replacing each other
+	for _, opts := range [][]ApplicationOption{feedOpts, proxyOpts} {
+		if _, err := NewApplication(context.Background(), opts...); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		opts []ApplicationOption
+		kind string
+	}{
+		{name: "feed", opts: feedOpts, kind: "archive"},
+		{name: "goproxy", opts: proxyOpts, kind: moduleKind},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := NewApplication(context.Background(), append(tc.opts, WithOffline())...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(a.Downloads) != 1 || a.Downloads[0].Kind != tc.kind {
+				t.Errorf("expected one download of kind %s, got %v", tc.kind, a.Downloads)
+			}
+		})
+	}
+}
+
+func TestOffline(t *testing.T) {
+	srv := newInstallTestServer(t, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cacheDir := t.TempDir()
+
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", t.TempDir()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	srv.Close()
+
+	for _, tc := range []struct {
+		name     string
+		opts     []ApplicationOption
+		cacheDir string
+		versions int
+	}{
+		{name: "offline", opts: []ApplicationOption{WithOffline()}, cacheDir: cacheDir, versions: 1},
+		{name: "unreachable", cacheDir: cacheDir, versions: 1},
+		{name: "offline-without-index", opts: []ApplicationOption{WithOffline()}, cacheDir: t.TempDir(), versions: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append(tc.opts, WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(tc.cacheDir))
+			a, err := NewApplication(context.Background(), opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(a.Downloads) != tc.versions {
+				t.Fatalf("expected %d versions, got %d", tc.versions, len(a.Downloads))
+			}
+			if tc.versions == 0 {
+				return
+			}
+			if _, err := a.Install(context.Background(), "1.22.1", t.TempDir()); err != nil {
+				t.Errorf("unexpected error installing from cache: %s", err)
+			}
+		})
+	}
+
+	// a server answering 404 is reachable, the cached index does not replace its answer
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	_, err = NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(notFound.URL+"/dl/"), WithCacheDir(cacheDir))
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code error 404, got %v", err)
+	}
+	if errors.Is(err, ErrNetwork) {
+		t.Errorf("expected 404 not to be a network error")
+	}
+}
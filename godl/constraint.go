@@ -0,0 +1,196 @@
+package godl
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	// comparator compares a version to a bound
+	comparator struct {
+		// op is one of =, <, <=, >, >=
+		op string
+		// bound to compare to
+		bound Version
+	}
+
+	// Constraint selects the newest version out of a list of versions. Supported are
+	// exact versions (1.22.3, 1.23rc1), the keywords latest, stable and oldstable, minor
+	// wildcards (1.22, 1.22.x) and ranges of comparators (>=1.21 <1.23)
+	Constraint struct {
+		// raw is the constraint as provided
+		raw string
+		// keyword is latest, stable or oldstable
+		keyword string
+		// comparators must all match
+		comparators []comparator
+	}
+)
+
+var (
+	wildcardRegex   = regexp.MustCompile(`^(?:go)?([1-9][0-9]{0,3})\.([0-9]{1,4})(?:\.x)?$`)
+	comparatorRegex = regexp.MustCompile(`^(>=|<=|==|=|>|<)?(.+)$`)
+)
+
+// matches returns true if v satisfies the comparator
+func (c comparator) matches(v Version) bool {
+	r := v.Compare(c.bound)
+	switch c.op {
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	}
+	return r == 0
+}
+
+// ParseConstraint parses a version constraint
+func ParseConstraint(s string) (*Constraint, error) {
+	raw := strings.TrimSpace(s)
+	c := &Constraint{raw: raw}
+	lower := strings.ToLower(raw)
+	switch lower {
+	case "":
+		return nil, errors.New("empty version constraint")
+	case "latest", "stable", "oldstable":
+		c.keyword = lower
+		return c, nil
+	}
+
+	if m := wildcardRegex.FindStringSubmatch(lower); m != nil {
+		major, _ := strconv.Atoi(m[1])
+		minor, _ := strconv.Atoi(m[2])
+		c.comparators = []comparator{
+			{op: ">=", bound: Version{Major: major, Minor: minor, Pre: PreBeta}},
+			{op: "<", bound: Version{Major: major, Minor: minor + 1, Pre: PreBeta}},
+		}
+		return c, nil
+	}
+
+	for _, field := range strings.Fields(strings.ReplaceAll(lower, ",", " ")) {
+		m := comparatorRegex.FindStringSubmatch(field)
+		v, err := Parse(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", raw, err)
+		}
+		c.comparators = append(c.comparators, comparator{op: m[1], bound: v})
+	}
+	return c, nil
+}
+
+// String returns the constraint as provided
+func (c *Constraint) String() string {
+	return c.raw
+}
+
+// Select returns the newest of versions satisfying the constraint, ErrVersionNotFound
+// is returned if no version matches
+func (c *Constraint) Select(versions []Version) (Version, error) {
+	newest := func(match func(v Version) bool) (Version, bool) {
+		var result Version
+		found := false
+		for _, v := range versions {
+			if match(v) && (!found || v.Compare(result) > 0) {
+				result, found = v, true
+			}
+		}
+		return result, found
+	}
+
+	var result Version
+	var found bool
+	switch c.keyword {
+	case "latest":
+		result, found = newest(func(v Version) bool { return true })
+	case "stable":
+		result, found = newest(Version.IsStable)
+	case "oldstable":
+		var stable Version
+		stable, found = newest(Version.IsStable)
+		if found {
+			result, found = newest(func(v Version) bool {
+				return v.IsStable() && (v.Major < stable.Major || (v.Major == stable.Major && v.Minor < stable.Minor))
+			})
+		}
+	default:
+		result, found = newest(func(v Version) bool {
+			for i := range c.comparators {
+				if !c.comparators[i].matches(v) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	if !found {
+		return Version{}, fmt.Errorf("%w: nothing matches %s", ErrVersionNotFound, c.raw)
+	}
+	return result, nil
+}
+
+// Resolve returns the newest known download satisfying constraint
+func (a *Application) Resolve(constraint string) (*Download, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]Version, len(a.Downloads))
+	for i := range a.Downloads {
+		versions[i] = a.Downloads[i].Version
+	}
+	v, err := c.Select(versions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range a.Downloads {
+		if a.Downloads[i].Version == v {
+			return &a.Downloads[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrVersionNotFound, constraint)
+}
+
+// installedVersions returns all versions installed in dir
+func installedVersions(dir string) ([]Version, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []Version
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if v, err := Parse(e.Name()); err == nil && v.String() == e.Name() {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+// ResolveInstalled returns the newest version installed in dir satisfying constraint,
+// ErrNotInstalled is returned if there is none
+func ResolveInstalled(constraint, dir string) (Version, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return Version{}, err
+	}
+	versions, err := installedVersions(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Version{}, err
+	}
+	v, err := c.Select(versions)
+	if errors.Is(err, ErrVersionNotFound) {
+		return Version{}, fmt.Errorf("%w: no go version matching %s in %s", ErrNotInstalled, constraint, dir)
+	}
+	return v, err
+}
@@ -0,0 +1,65 @@
+package godl
+
+import (
+	"errors"
+	"testing"
+)
+
+var testVersions = []Version{
+	mustParse("1.20"), mustParse("1.20.14"), mustParse("1.21rc2"), mustParse("1.21.0"), mustParse("1.21.13"),
+	mustParse("1.22.0"), mustParse("1.22.9"), mustParse("1.22.10"), mustParse("1.23rc1"), mustParse("1.23beta1"),
+}
+
+var testCasesConstraint = []struct {
+	name       string
+	constraint string
+	expected   string
+	notFound   bool
+}{
+	{name: "exact", constraint: "1.22.0", expected: "1.22.0"},
+	{name: "exact-rc", constraint: "1.21rc2", expected: "1.21rc2"},
+	{name: "latest", constraint: "latest", expected: "1.23rc1"},
+	{name: "stable", constraint: "stable", expected: "1.22.10"},
+	{name: "oldstable", constraint: "oldstable", expected: "1.21.13"},
+	{name: "minor", constraint: "1.22", expected: "1.22.10"},
+	{name: "minor-x", constraint: "1.21.x", expected: "1.21.13"},
+	{name: "minor-old-style", constraint: "1.20", expected: "1.20.14"},
+	{name: "go-prefix", constraint: "go1.22", expected: "1.22.10"},
+	{name: "range", constraint: ">=1.21 <1.22", expected: "1.21.13"},
+	{name: "range-rc", constraint: ">=1.21rc1 <=1.21rc2", expected: "1.21rc2"},
+	{name: "range-comma", constraint: ">1.20.14, <1.21.0", expected: "1.21rc2"},
+	{name: "not-found", constraint: "1.19", notFound: true},
+	{name: "range-not-found", constraint: ">1.23", notFound: true},
+}
+
+func TestConstraintSelect(t *testing.T) {
+	for _, tc := range testCasesConstraint {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ParseConstraint(tc.constraint)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			v, err := c.Select(testVersions)
+			if tc.notFound {
+				if !errors.Is(err, ErrVersionNotFound) {
+					t.Errorf("expected ErrVersionNotFound, got %v (%s)", err, v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if v.String() != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, v)
+			}
+		})
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for _, s := range []string{"", ">=abc", "1.x.2", "<"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package godl
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// versionFiles are looked up in each directory, the first one found wins
+var versionFiles = []string{".go-version", "go.work", "go.mod"}
+
+// DiscoverVersion walks up from dir and returns the version constraint required by the
+// first .go-version, go.work or go.mod file found along with the path of that file.
+//
+// A toolchain directive selects exactly that toolchain. A go directive selects the newest
+// patch release of its minor version that is at least the given version, e.g. go 1.22.3
+// resolves to >=1.22.3 <1.23beta1. A .go-version file holds a single version or constraint.
+// ErrNoVersionFile is returned if there is no such file up to the root of the file system
+func DiscoverVersion(dir string) (constraint string, path string, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		for _, name := range versionFiles {
+			p := filepath.Join(dir, name)
+			data, err := os.ReadFile(p)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return "", "", err
+			}
+			constraint, err := versionFromFile(p, data)
+			if err != nil {
+				return "", "", err
+			}
+			return constraint, p, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("%w: no .go-version, go.work or go.mod found", ErrNoVersionFile)
+		}
+		dir = parent
+	}
+}
+
+// versionFromFile returns the version constraint contained in the file p
+func versionFromFile(p string, data []byte) (string, error) {
+	var goVersion, toolchain string
+	switch filepath.Base(p) {
+	case ".go-version":
+		constraint := strings.TrimSpace(string(data))
+		if _, err := ParseConstraint(constraint); err != nil {
+			return "", fmt.Errorf("error reading %s: %w", p, err)
+		}
+		return constraint, nil
+	case "go.work":
+		f, err := modfile.ParseWork(p, data, nil)
+		if err != nil {
+			return "", err
+		}
+		if f.Go != nil {
+			goVersion = f.Go.Version
+		}
+		if f.Toolchain != nil {
+			toolchain = f.Toolchain.Name
+		}
+	default:
+		f, err := modfile.Parse(p, data, nil)
+		if err != nil {
+			return "", err
+		}
+		if f.Go != nil {
+			goVersion = f.Go.Version
+		}
+		if f.Toolchain != nil {
+			toolchain = f.Toolchain.Name
+		}
+	}
+
+	if toolchain != "" && toolchain != "default" {
+		// toolchain names may carry a suffix like go1.22.3+auto
+		name, _, _ := strings.Cut(toolchain, "+")
+		v, err := Parse(name)
+		if err != nil {
+			return "", fmt.Errorf("error reading toolchain directive in %s: %w", p, err)
+		}
+		return v.String(), nil
+	}
+	if goVersion == "" {
+		return "", fmt.Errorf("%w: %s has no go or toolchain directive", ErrNoVersionFile, p)
+	}
+	v, err := Parse(goVersion)
+	if err != nil {
+		return "", fmt.Errorf("error reading go directive in %s: %w", p, err)
+	}
+	next := Version{Major: v.Major, Minor: v.Minor + 1, Pre: PreBeta, PreNum: 1}
+	return fmt.Sprintf(">=%s <%s", v, next), nil
+}
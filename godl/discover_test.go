@@ -0,0 +1,106 @@
+package godl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testCasesDiscoverVersion = []struct {
+	name     string
+	files    map[string]string
+	dir      string
+	expected string
+	source   string
+	err      error
+}{
+	{
+		name:     "go-directive",
+		files:    map[string]string{"go.mod": "module example.com/m\n\ngo 1.22.3\n"},
+		expected: ">=1.22.3 <1.23beta1",
+		source:   "go.mod",
+	},
+	{
+		name:     "go-directive-minor",
+		files:    map[string]string{"go.mod": "module example.com/m\n\ngo 1.20\n"},
+		expected: ">=1.20 <1.21beta1",
+		source:   "go.mod",
+	},
+	{
+		name:     "toolchain",
+		files:    map[string]string{"go.mod": "module example.com/m\n\ngo 1.21\n\ntoolchain go1.22.5\n"},
+		expected: "1.22.5",
+		source:   "go.mod",
+	},
+	{
+		name: "go-work-first",
+		files: map[string]string{
+			"go.work": "go 1.23.1\n\nuse ./a\n",
+			"go.mod":  "module example.com/m\n\ngo 1.21.0\n",
+		},
+		expected: ">=1.23.1 <1.24beta1",
+		source:   "go.work",
+	},
+	{
+		name: "go-version-first",
+		files: map[string]string{
+			".go-version": "go1.21.4\n",
+			"go.mod":      "module example.com/m\n\ngo 1.21.0\n",
+		},
+		expected: "go1.21.4",
+		source:   ".go-version",
+	},
+	{
+		name: "nearest",
+		files: map[string]string{
+			"go.work":       "go 1.23.1\n",
+			"a/go.mod":      "module example.com/a\n\ngo 1.21.0\n",
+			"a/pkg/x/x.txt": "",
+		},
+		dir:      "a/pkg/x",
+		expected: ">=1.21.0 <1.22beta1",
+		source:   "a/go.mod",
+	},
+	{
+		name:  "invalid-go-version",
+		files: map[string]string{".go-version": "banana\n"},
+		err:   errors.New("invalid"),
+	},
+}
+
+func TestDiscoverVersion(t *testing.T) {
+	for _, tc := range testCasesDiscoverVersion {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tc.files {
+				p := filepath.Join(root, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			constraint, p, err := DiscoverVersion(filepath.Join(root, filepath.FromSlash(tc.dir)))
+			if tc.err != nil {
+				if err == nil {
+					t.Fatalf("expected error, got %s from %s", constraint, p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if constraint != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, constraint)
+			}
+			if p != filepath.Join(root, filepath.FromSlash(tc.source)) {
+				t.Errorf("expected version from %s, got %s", tc.source, p)
+			}
+			if _, err := ParseConstraint(constraint); err != nil {
+				t.Errorf("discovered constraint is invalid: %s", err)
+			}
+		})
+	}
+}
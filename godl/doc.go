@@ -0,0 +1,37 @@
+// Package godl downloads, verifies and installs Go releases.
+//
+// An Application is created with NewApplication and configured using
+// functional options such as WithLogger or WithIncludeReleaseCandidates.
+// On construction it queries the list of available releases for the
+// current operating system and architecture, which is then available as
+// Application.Downloads. WithPlatform selects releases for another platform,
+// WithMirrors replaces go.dev with mirrors that are tried in order and
+// WithGoProxy fetches toolchain modules from a GOPROXY list instead.
+//
+// Install downloads a release, verifies its published SHA-256 checksum and
+// extracts it to <dir>/<version>. Use points a symbolic link (a copy on
+// Windows) at an installed version:
+//
+//	a, err := godl.NewApplication(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	if _, err := a.Install(ctx, "1.22.1", "/opt/go"); err != nil {
+//		return err
+//	}
+//	return a.Use("1.22.1", "/opt/go", "current")
+//
+// WithProgress reports the progress of downloads and extractions, e.g. to
+// render a progress bar, WithConnections downloads large archives using
+// concurrent range requests.
+//
+// NewMirrorHandler serves the archives of a Cache as a release mirror other
+// applications install from using WithBaseUrl.
+//
+// ListInstalled, RemoveInstalled and PruneInstalled manage the versions
+// installed in a directory without querying any release.
+//
+// Errors can be inspected using errors.Is with ErrVersionNotFound,
+// ErrChecksumMismatch, ErrNetwork, ErrArchiveCorrupt, ErrAlreadyInstalled,
+// ErrNotInstalled and ErrVersionInUse.
+package godl
@@ -0,0 +1,306 @@
+package godl
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// partialSuffix is appended to the file name of incomplete downloads
+	partialSuffix = ".partial"
+	// maxRetryDelay caps the exponential backoff between retries
+	maxRetryDelay = 30 * time.Second
+)
+
+// retryBaseDelay is the delay before the first retry, doubled for each further retry
+var retryBaseDelay = time.Second
+
+// networkReader marks errors reading from the wrapped reader as network errors
+type networkReader struct {
+	r io.Reader
+}
+
+// Read implements io.Reader
+func (n networkReader) Read(p []byte) (int, error) {
+	c, err := n.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: error reading response: %w", ErrNetwork, err)
+	}
+	return c, err
+}
+
+// httpGet requests u using client starting at offset, any response other than 200 or
+// 206 is returned as an error
+func httpGet(ctx context.Context, client *http.Client, u string, offset int64) (*http.Response, error) {
+	byteRange := ""
+	if offset > 0 {
+		byteRange = fmt.Sprintf("bytes=%d-", offset)
+	}
+	return httpDo(ctx, client, http.MethodGet, u, byteRange)
+}
+
+// httpDo sends a method request for u using client, byteRange is sent as Range header if
+// not empty. Any response other than 200 or 206 is returned as an error
+func httpDo(ctx context.Context, client *http.Client, method, u, byteRange string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		_ = res.Body.Close()
+		return nil, &StatusError{StatusCode: res.StatusCode, Status: res.Status}
+	}
+	return res, nil
+}
+
+// isTransient returns true if a request failing with err should be retried
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// status codes only match ErrNetwork for server errors and rate limiting
+	return errors.Is(err, ErrNetwork)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// DownloadGoArchive saves a Go release archive to given writer and verifies
+// the published sha256 checksum while writing
+func (d *Download) DownloadGoArchive(ctx context.Context, writer io.Writer) error {
+	if d.Sha256 == "" {
+		return fmt.Errorf("%w: no published checksum for %s, refusing to download", ErrChecksumMismatch, d.FileName)
+	}
+	urls, mirrors := d.urls()
+	res, err := httpGet(ctx, d.Client, urls[0].String(), 0)
+	if mirrors != nil {
+		d.mirrors.report(mirrors[0], err)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := res.Body.Close()
+		if err != nil {
+			d.Logger.Warn("error closing http body", "err", err)
+		}
+	}()
+	h := sha256.New()
+	progress := newProgressReporter(d.Progress, PhaseDownload, d.FileName, cmp.Or(d.Size, max(res.ContentLength, 0)))
+	var w io.Writer = io.MultiWriter(writer, h)
+	if progress != nil {
+		w = progressWriter{w: w, r: progress}
+	}
+	_, err = io.Copy(w, networkReader{res.Body})
+	if err != nil {
+		return fmt.Errorf("error writing bytes to file: %w", err)
+	}
+	progress.done()
+	return d.verify(h.Sum(nil))
+}
+
+// verify compares sum with the published checksum
+func (d *Download) verify(sum []byte) error {
+	actual := hex.EncodeToString(sum)
+	if !strings.EqualFold(actual, d.Sha256) {
+		return &ChecksumMismatchError{FileName: d.FileName, Expected: d.Sha256, Actual: actual}
+	}
+	return nil
+}
+
+// urls returns the urls the download is available from in the order of preference, along
+// with their mirrors if known
+func (d *Download) urls() ([]*url.URL, []*mirror) {
+	if d.mirrors == nil {
+		return []*url.URL{d.Url}, nil
+	}
+	return d.mirrors.urls(d.FileName)
+}
+
+// DownloadGoArchiveToFile saves a Go release archive as fileName. Data is written to
+// fileName.partial first, an existing partial file is resumed using a range request.
+// Failing mirrors are skipped, transient errors are retried with exponential backoff.
+// The file is only moved to fileName after its checksum was verified, on mismatch the
+// partial file is removed
+func (d *Download) DownloadGoArchiveToFile(ctx context.Context, fileName string) error {
+	if d.Sha256 == "" {
+		return fmt.Errorf("%w: no published checksum for %s, refusing to download", ErrChecksumMismatch, d.FileName)
+	}
+	partialFileName := fileName + partialSuffix
+	if err := d.downloadWithRetries(ctx, partialFileName); err != nil {
+		return err
+	}
+
+	sum, err := fileSha256(partialFileName)
+	if err != nil {
+		return err
+	}
+	if err := d.verify(sum); err != nil {
+		if removeErr := os.Remove(partialFileName); removeErr != nil {
+			d.Logger.Warn("error removing failed download", "err", removeErr, "file", partialFileName)
+		}
+		return err
+	}
+	return os.Rename(partialFileName, fileName)
+}
+
+// downloadWithRetries downloads to partialFileName, transient errors are retried with
+// exponential backoff
+func (d *Download) downloadWithRetries(ctx context.Context, partialFileName string) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = d.downloadFromMirrors(ctx, partialFileName)
+		if err == nil || !isTransient(err) || attempt >= d.Retries {
+			return err
+		}
+		delay := min(retryBaseDelay<<attempt, maxRetryDelay)
+		d.Logger.Warn("download failed, retrying", "err", err, "attempt", attempt+1, "delay", delay)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// downloadFromMirrors tries all healthy mirrors in order until one succeeds
+func (d *Download) downloadFromMirrors(ctx context.Context, partialFileName string) error {
+	urls, mirrors := d.urls()
+	var err error
+	for i, u := range urls {
+		err = d.download(ctx, u.String(), partialFileName)
+		if mirrors != nil {
+			d.mirrors.report(mirrors[i], err)
+		}
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if i+1 < len(urls) {
+			d.Logger.Warn("download from mirror failed, trying next one", "url", u.String(), "err", err)
+		}
+	}
+	return err
+}
+
+// download fetches u to partialFileName using parallel range requests if possible, a single
+// stream resuming partialFileName otherwise
+func (d *Download) download(ctx context.Context, u, partialFileName string) error {
+	if d.Connections > 1 {
+		ok, err := d.downloadParallel(ctx, u, partialFileName)
+		if ok || err != nil {
+			return err
+		}
+	}
+	return d.downloadPartial(ctx, u, partialFileName)
+}
+
+// downloadPartial appends the missing data from u to partialFileName
+func (d *Download) downloadPartial(ctx context.Context, u, partialFileName string) error {
+	f, err := os.OpenFile(partialFileName, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	defer func() {
+		err := f.Close()
+		if err != nil {
+			d.Logger.Warn("error closing downloaded file", "err", err)
+		}
+	}()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if d.Size > 0 && offset == d.Size {
+		return nil
+	}
+	if d.Size > 0 && offset > d.Size {
+		offset = 0
+	}
+
+	res, err := httpGet(ctx, d.Client, u, offset)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		// the partial file is complete or unusable, start from scratch
+		d.Logger.Debug("range not satisfiable, restarting download", "file", partialFileName)
+		offset = 0
+		res, err = httpGet(ctx, d.Client, u, offset)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := res.Body.Close()
+		if err != nil {
+			d.Logger.Warn("error closing http body", "err", err)
+		}
+	}()
+
+	if offset > 0 && res.StatusCode == http.StatusPartialContent {
+		d.Logger.Debug("resuming download", "file", partialFileName, "offset", offset)
+	} else {
+		// the server ignored the range request, data starts at the beginning
+		offset = 0
+	}
+	if err := f.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	total := d.Size
+	if total == 0 && res.ContentLength > 0 {
+		total = offset + res.ContentLength
+	}
+	progress := newProgressReporter(d.Progress, PhaseDownload, d.FileName, total)
+	var w io.Writer = f
+	if progress != nil {
+		progress.addBytes(offset)
+		w = progressWriter{w: f, r: progress}
+	}
+	if _, err := io.Copy(w, networkReader{res.Body}); err != nil {
+		return fmt.Errorf("error writing bytes to file: %w", err)
+	}
+	progress.done()
+	return nil
+}
+
+// fileSha256 returns the sha256 checksum of a file
+func fileSha256(fileName string) ([]byte, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
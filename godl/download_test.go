@@ -0,0 +1,379 @@
+package godl
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadGoArchiveToFile(t *testing.T) {
+	content := []byte("not really a go archive")
+	sum := sha256.Sum256(content)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL + "/dl/go1.22.1.linux-amd64.tar.gz")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tc := range []struct {
+		name     string
+		sha256   string
+		mismatch bool
+	}{
+		{name: "match", sha256: hex.EncodeToString(sum[:])},
+		{name: "mismatch", sha256: "0000", mismatch: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := Download{Url: u, FileName: "go1.22.1.linux-amd64.tar.gz", Sha256: tc.sha256, Logger: logger}
+			fileName := filepath.Join(t.TempDir(), d.FileName)
+			err := d.DownloadGoArchiveToFile(context.Background(), fileName)
+			var mismatchErr *ChecksumMismatchError
+			if errors.As(err, &mismatchErr) != tc.mismatch || errors.Is(err, ErrChecksumMismatch) != tc.mismatch {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, statErr := os.Stat(fileName)
+			if tc.mismatch && !errors.Is(statErr, fs.ErrNotExist) {
+				t.Errorf("expected %s to be removed", fileName)
+			}
+			if !tc.mismatch && statErr != nil {
+				t.Errorf("expected %s to exist: %s", fileName, statErr)
+			}
+		})
+	}
+}
+
+func TestDownloadGoArchiveToFileResume(t *testing.T) {
+	setRetryBaseDelay(t, time.Millisecond)
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := sha256.Sum256(content)
+	var mu sync.Mutex
+	var requests, failures int
+	var servedBytes int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		cw := &countingResponseWriter{ResponseWriter: w}
+		http.ServeContent(cw, r, "archive", time.Time{}, bytes.NewReader(content))
+		servedBytes += cw.n
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL + "/dl/go1.22.1.linux-amd64.tar.gz")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tc := range []struct {
+		name     string
+		partial  []byte
+		failures int
+		retries  int
+		requests int
+		served   int64
+		fail     bool
+	}{
+		{name: "resume", partial: content[:4000], requests: 1, served: 6000},
+		{name: "retry", failures: 2, retries: 3, requests: 3, served: 10000},
+		{name: "retries-exhausted", failures: 2, retries: 1, requests: 2, fail: true},
+		{name: "complete-partial", partial: content, requests: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mu.Lock()
+			requests, failures, servedBytes = 0, tc.failures, 0
+			mu.Unlock()
+			d := Download{Url: u, FileName: "go1.22.1.linux-amd64.tar.gz", Sha256: hex.EncodeToString(sum[:]), Size: int64(len(content)), Retries: tc.retries, Logger: logger}
+			fileName := filepath.Join(t.TempDir(), d.FileName)
+			if tc.partial != nil {
+				if err := os.WriteFile(fileName+partialSuffix, tc.partial, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := d.DownloadGoArchiveToFile(context.Background(), fileName)
+			if (err != nil) != tc.fail {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			mu.Lock()
+			if requests != tc.requests {
+				t.Errorf("expected %d requests, got %d", tc.requests, requests)
+			}
+			if servedBytes != tc.served {
+				t.Errorf("expected %d bytes served, got %d", tc.served, servedBytes)
+			}
+			mu.Unlock()
+			if tc.fail {
+				return
+			}
+			data, err := os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, content) {
+				t.Error("downloaded data does not match")
+			}
+			if _, err := os.Stat(fileName + partialSuffix); !errors.Is(err, fs.ErrNotExist) {
+				t.Error("expected partial file to be promoted")
+			}
+		})
+	}
+}
+
+func TestDownloadGoArchiveToFileParallel(t *testing.T) {
+	setMinParallelSize(t, 1000)
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := sha256.Sum256(content)
+	var mu sync.Mutex
+	var heads, gets int
+	var servedBytes int64
+	ranges := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodHead {
+			heads++
+		} else {
+			gets++
+		}
+		cw := &countingResponseWriter{ResponseWriter: w}
+		if ranges {
+			http.ServeContent(cw, r, "archive", time.Time{}, bytes.NewReader(content))
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			if r.Method == http.MethodGet {
+				_, _ = cw.Write(content)
+			}
+		}
+		servedBytes += cw.n
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL + "/dl/go1.22.1.linux-amd64.tar.gz")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tc := range []struct {
+		name        string
+		ranges      bool
+		connections int
+		sha256      string
+		heads, gets int
+		mismatch    bool
+	}{
+		{name: "ranges", ranges: true, connections: 4, heads: 1, gets: 4},
+		{name: "uneven-ranges", ranges: true, connections: 3, heads: 1, gets: 3},
+		{name: "no-ranges", connections: 4, heads: 1, gets: 1},
+		{name: "single-connection", ranges: true, connections: 1, gets: 1},
+		{name: "mismatch", ranges: true, connections: 4, sha256: "0000", heads: 1, gets: 4, mismatch: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mu.Lock()
+			heads, gets, servedBytes, ranges = 0, 0, 0, tc.ranges
+			mu.Unlock()
+			var reports []Progress
+			d := Download{
+				Url:         u,
+				FileName:    "go1.22.1.linux-amd64.tar.gz",
+				Sha256:      cmp.Or(tc.sha256, hex.EncodeToString(sum[:])),
+				Connections: tc.connections,
+				Logger:      logger,
+				Progress:    func(p Progress) { reports = append(reports, p) },
+			}
+			fileName := filepath.Join(t.TempDir(), d.FileName)
+			err := d.DownloadGoArchiveToFile(context.Background(), fileName)
+			if errors.Is(err, ErrChecksumMismatch) != tc.mismatch || (!tc.mismatch && err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			mu.Lock()
+			if heads != tc.heads || gets != tc.gets {
+				t.Errorf("expected %d HEAD and %d GET requests, got %d and %d", tc.heads, tc.gets, heads, gets)
+			}
+			if servedBytes != int64(len(content)) {
+				t.Errorf("expected %d bytes served, got %d", len(content), servedBytes)
+			}
+			mu.Unlock()
+			if last := reports[len(reports)-1]; !last.Done || last.Bytes != int64(len(content)) {
+				t.Errorf("unexpected last progress report %+v", last)
+			}
+			entries, err := os.ReadDir(filepath.Dir(fileName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.mismatch {
+				if len(entries) != 0 {
+					t.Errorf("expected no files left, got %v", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Errorf("expected only the archive, got %v", entries)
+			}
+			data, err := os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, content) {
+				t.Error("downloaded data does not match")
+			}
+		})
+	}
+}
+
+func TestDownloadGoArchiveToFileParallelResume(t *testing.T) {
+	setMinParallelSize(t, 1000)
+	setRetryBaseDelay(t, time.Millisecond)
+	content := make([]byte, 100000)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	sum := sha256.Sum256(content)
+	var mu sync.Mutex
+	var ranges []string
+	var servedBytes int64
+	var abort bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		limit := int64(-1)
+		if r.Method == http.MethodGet {
+			ranges = append(ranges, r.Header.Get("Range"))
+			// the third range fails after 1000 bytes once
+			if abort && r.Header.Get("Range") == "bytes=50000-74999" {
+				abort, limit = false, 1000
+			}
+		}
+		mu.Unlock()
+		cw := &countingResponseWriter{ResponseWriter: w, limit: limit}
+		http.ServeContent(cw, r, "archive", time.Time{}, bytes.NewReader(content))
+		mu.Lock()
+		servedBytes += cw.n
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL + "/dl/go1.22.1.linux-amd64.tar.gz")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, tc := range []struct {
+		name        string
+		minParallel int64
+		ranges      []string
+		served      int64
+	}{
+		{name: "parallel", minParallel: 1000, ranges: []string{"bytes=51000-74999"}, served: 24000},
+		// the server no longer qualifies for parallel downloads, the bytes complete from the
+		// start are resumed using a single stream
+		{name: "single-stream", minParallel: int64(len(content)) + 1, ranges: []string{"bytes=51000-"}, served: 49000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mu.Lock()
+			ranges, servedBytes, abort = nil, 0, true
+			mu.Unlock()
+			minParallelSize = 1000
+			d := Download{
+				Url:         u,
+				FileName:    "go1.22.1.linux-amd64.tar.gz",
+				Sha256:      hex.EncodeToString(sum[:]),
+				Size:        int64(len(content)),
+				Connections: 4,
+				Logger:      logger,
+			}
+			fileName := filepath.Join(t.TempDir(), d.FileName)
+			if err := d.DownloadGoArchiveToFile(context.Background(), fileName); !errors.Is(err, ErrNetwork) {
+				t.Fatalf("expected ErrNetwork, got %v", err)
+			}
+			data, err := os.ReadFile(fileName + ".ranges" + partialSuffix)
+			if err != nil {
+				t.Fatalf("expected progress of ranges to be saved: %s", err)
+			}
+			var state rangeState
+			if err := json.Unmarshal(data, &state); err != nil {
+				t.Fatal(err)
+			}
+			if state.written() != 76000 {
+				t.Errorf("expected 76000 bytes written, got %d", state.written())
+			}
+
+			mu.Lock()
+			ranges, servedBytes = nil, 0
+			mu.Unlock()
+			minParallelSize = tc.minParallel
+			if err := d.DownloadGoArchiveToFile(context.Background(), fileName); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			data, err = os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, content) {
+				t.Error("downloaded data does not match")
+			}
+			mu.Lock()
+			if !slices.Equal(ranges, tc.ranges) {
+				t.Errorf("expected requests for %v, got %v", tc.ranges, ranges)
+			}
+			if servedBytes != tc.served {
+				t.Errorf("expected only the %d missing bytes to be served, got %d", tc.served, servedBytes)
+			}
+			mu.Unlock()
+			entries, err := os.ReadDir(filepath.Dir(fileName))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("expected only the archive, got %v", entries)
+			}
+		})
+	}
+}
+
+// setMinParallelSize lowers minParallelSize to size for the duration of the test
+func setMinParallelSize(t *testing.T, size int64) {
+	previous := minParallelSize
+	minParallelSize = size
+	t.Cleanup(func() { minParallelSize = previous })
+}
+
+// setRetryBaseDelay lowers retryBaseDelay to d for the duration of the test
+func setRetryBaseDelay(t *testing.T, d time.Duration) {
+	previous := retryBaseDelay
+	retryBaseDelay = d
+	t.Cleanup(func() { retryBaseDelay = previous })
+}
+
+// countingResponseWriter counts the bytes written to the response, the connection is
+// aborted after limit bytes if limit is positive
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n     int64
+	limit int64
+}
+
+// Write implements io.Writer
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	if c.limit > 0 && c.n+int64(len(p)) > c.limit {
+		p = p[:c.limit-c.n]
+	}
+	n, err := c.ResponseWriter.Write(p)
+	c.n += int64(n)
+	if c.limit > 0 && c.n == c.limit {
+		http.NewResponseController(c.ResponseWriter).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	return n, err
+}
@@ -0,0 +1,74 @@
+package godl
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrVersionNotFound is returned when a requested go version is not available
+	ErrVersionNotFound = errors.New("no such go version")
+	// ErrChecksumMismatch is returned when a download does not match its published checksum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrNetwork is returned when a remote server cannot be reached or answers with a server error
+	ErrNetwork = errors.New("network error")
+	// ErrArchiveCorrupt is returned when an archive cannot be read or contains invalid entries
+	ErrArchiveCorrupt = errors.New("archive corrupt")
+	// ErrAlreadyInstalled is returned when installing a version that already exists
+	ErrAlreadyInstalled = errors.New("version already installed")
+	// ErrNotInstalled is returned when a version is required to be installed but is not
+	ErrNotInstalled = errors.New("version not installed")
+	// ErrVersionInUse is returned when removing the version the symbolic link points at
+	ErrVersionInUse = errors.New("version in use")
+	// ErrLinkExists is returned when the link path exists and is not a symbolic link
+	ErrLinkExists = errors.New("exists and is not a symbolic link")
+	// ErrLocked is returned when another process holds the lock on a destination or version
+	ErrLocked = errors.New("locked by another process")
+	// ErrNoVersionFile is returned when no file declaring the required go version is found
+	ErrNoVersionFile = errors.New("no version file")
+)
+
+// StatusError is returned when a server answers with an unexpected status code
+type StatusError struct {
+	// StatusCode of response
+	StatusCode int
+	// Status of response
+	Status string
+}
+
+// Error implements error
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code error: %d %s", e.StatusCode, e.Status)
+}
+
+// Is allows matching ErrNetwork using errors.Is for server errors and rate limiting, other
+// status codes such as 404 are answers of a reachable server
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNetwork && (e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests)
+}
+
+// ChecksumMismatchError is returned when a downloaded archive does not match the published checksum
+type ChecksumMismatchError struct {
+	// FileName of download
+	FileName string
+	// Expected is the published checksum, a go.sum style hash for toolchain modules
+	Expected string
+	// Actual is the checksum of the downloaded data
+	Actual string
+}
+
+// Error implements error
+func (e *ChecksumMismatchError) Error() string {
+	if strings.HasPrefix(e.Expected, "h1:") {
+		// go.sum style hash of a toolchain module
+		return fmt.Sprintf("checksum mismatch for %s: expected %s, got %s", e.FileName, e.Expected, e.Actual)
+	}
+	return fmt.Sprintf("checksum mismatch for %s: expected sha256 %s, got %s", e.FileName, e.Expected, e.Actual)
+}
+
+// Is allows matching ErrChecksumMismatch using errors.Is
+func (e *ChecksumMismatchError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
@@ -0,0 +1,47 @@
+package godl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/sascha-andres/godl/godl"
+)
+
+func ExampleNewApplication() {
+	a, err := godl.NewApplication(context.Background(), godl.WithIncludeReleaseCandidates())
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := range a.Downloads {
+		fmt.Println(a.Downloads[i].Version, a.Downloads[i].Url)
+	}
+}
+
+func ExampleApplication_Install() {
+	a, err := godl.NewApplication(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	p, err := a.Install(context.Background(), "1.22.1", "/opt/go")
+	if errors.Is(err, godl.ErrAlreadyInstalled) {
+		fmt.Println("1.22.1 is already installed")
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("installed to", p)
+}
+
+func ExampleApplication_Use() {
+	a, err := godl.NewApplication(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	// /opt/go/current will point to /opt/go/1.22.1
+	if err := a.Use("1.22.1", "/opt/go", "current"); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,80 @@
+package godl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// feedUrl returns the url of the JSON release feed of m
+func feedUrl(m *mirror) string {
+	u := *m.base
+	u.RawQuery = "mode=json&include=all"
+	return u.String()
+}
+
+// queryFeed reads all known go versions from the JSON release feed of m
+func (a *Application) queryFeed(ctx context.Context, m *mirror) error {
+	res, err := httpGet(ctx, a.httpClient, feedUrl(m), 0)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := res.Body.Close()
+		if err != nil {
+			a.logger.Warn("error closing http response body", "err", err)
+		}
+	}()
+
+	var releases []release
+	if err := json.NewDecoder(networkReader{res.Body}).Decode(&releases); err != nil {
+		return fmt.Errorf("error decoding release feed: %w", err)
+	}
+	for i := range releases {
+		a.processRelease(m, releases[i])
+	}
+	if a.cache != nil {
+		if err := a.cache.saveIndex(releaseIndexFile, releases); err != nil {
+			a.logger.Warn("error saving release index to cache", "err", err)
+		}
+	}
+	return nil
+}
+
+// processRelease is transforming the files of a release to our internal version representation
+// it will skip over files that are not archives for the selected OS or arch
+func (a *Application) processRelease(m *mirror, r release) {
+	for _, f := range r.Files {
+		if f.Kind != "archive" {
+			continue
+		}
+		if f.Os != a.goos || f.Arch != a.goarch {
+			continue
+		}
+		if !a.versionRegex.MatchString(f.FileName) {
+			continue
+		}
+		v, err := Parse(f.Version)
+		if err != nil {
+			a.logger.Debug("skipping release with unknown version format", "version", f.Version)
+			continue
+		}
+
+		a.Downloads = append(a.Downloads, Download{
+			Url:         m.base.JoinPath(f.FileName),
+			Version:     v,
+			GoOs:        f.Os,
+			GoArch:      f.Arch,
+			FileName:    f.FileName,
+			Kind:        f.Kind,
+			Size:        f.Size,
+			Sha256:      f.Sha256,
+			Logger:      a.logger,
+			Client:      a.httpClient,
+			Retries:     a.retries,
+			Progress:    a.progress,
+			Connections: a.connections,
+			mirrors:     a.mirrors,
+		})
+	}
+}
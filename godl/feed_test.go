@@ -0,0 +1,106 @@
+package godl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+const testFeed = `[
+  {"version": "go1.22.1", "stable": true, "files": [
+    {"filename": "go1.22.1.src.tar.gz", "os": "", "arch": "", "version": "go1.22.1", "sha256": "aaa", "size": 1, "kind": "source"},
+    {"filename": "go1.22.1.%[1]s-%[2]s.tar.gz", "os": "%[1]s", "arch": "%[2]s", "version": "go1.22.1", "sha256": "bbb", "size": 2, "kind": "archive"},
+    {"filename": "go1.22.1.%[1]s-%[2]s.msi", "os": "%[1]s", "arch": "%[2]s", "version": "go1.22.1", "sha256": "ccc", "size": 3, "kind": "installer"},
+    {"filename": "go1.22.1.plan9-mips.tar.gz", "os": "plan9", "arch": "mips", "version": "go1.22.1", "sha256": "ddd", "size": 4, "kind": "archive"}
+  ]},
+  {"version": "go1.23rc1", "stable": false, "files": [
+    {"filename": "go1.23rc1.%[1]s-%[2]s.tar.gz", "os": "%[1]s", "arch": "%[2]s", "version": "go1.23rc1", "sha256": "eee", "size": 5, "kind": "archive"}
+  ]}
+]`
+
+const testDownloadPage = `<html><body><table><tr>
+<td class="filename"><a class="download" href="/dl/go1.21.0.%[1]s-%[2]s.tar.gz">go1.21.0.%[1]s-%[2]s.tar.gz</a></td>
+<td><tt>fff</tt></td>
+</tr></table></body></html>`
+
+// newTestServer returns a server serving the feed and/or the download page
+func newTestServer(t *testing.T, feed bool) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") == "json" {
+			if !feed {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = fmt.Fprintf(w, testFeed, runtime.GOOS, runtime.GOARCH)
+			return
+		}
+		_, _ = fmt.Fprintf(w, testDownloadPage, runtime.GOOS, runtime.GOARCH)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestQueryVersions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tc := range []struct {
+		name     string
+		feed     bool
+		opts     []ApplicationOption
+		versions []string
+		sha256   string
+	}{
+		{name: "feed", feed: true, versions: []string{"1.22.1"}, sha256: "bbb"},
+		{name: "feed-rc", feed: true, opts: []ApplicationOption{WithIncludeReleaseCandidates()}, versions: []string{"1.23rc1", "1.22.1"}, sha256: "eee"},
+		{name: "fallback", feed: false, versions: []string{"1.21.0"}, sha256: "fff"},
+		{name: "feed-platform", feed: true, opts: []ApplicationOption{WithPlatform("plan9", "mips")}, versions: []string{"1.22.1"}, sha256: "ddd"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTestServer(t, tc.feed)
+			a, err := NewApplication(context.Background(), append(tc.opts, WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(a.Downloads) != len(tc.versions) {
+				t.Fatalf("expected %d downloads, got %d", len(tc.versions), len(a.Downloads))
+			}
+			for i := range tc.versions {
+				if a.Downloads[i].Version.String() != tc.versions[i] {
+					t.Errorf("expected version %s at %d, got %s", tc.versions[i], i, a.Downloads[i].Version)
+				}
+				if a.Downloads[i].Kind != "archive" {
+					t.Errorf("expected kind archive, got %s", a.Downloads[i].Kind)
+				}
+			}
+			if a.Downloads[0].Sha256 != tc.sha256 {
+				t.Errorf("expected sha256 %q, got %q", tc.sha256, a.Downloads[0].Sha256)
+			}
+			expectedUrl := srv.URL + "/dl/" + a.Downloads[0].FileName
+			if a.Downloads[0].Url.String() != expectedUrl {
+				t.Errorf("expected url %s, got %s", expectedUrl, a.Downloads[0].Url.String())
+			}
+		})
+	}
+}
+
+func TestQueryVersionsErrors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := newTestServer(t, true)
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.GetDownload(context.Background(), "1.0.1"); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("expected ErrVersionNotFound, got %v", err)
+	}
+
+	srv.Close()
+	if _, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir())); !errors.Is(err, ErrNetwork) {
+		t.Errorf("expected ErrNetwork, got %v", err)
+	}
+}
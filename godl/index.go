@@ -0,0 +1,117 @@
+package godl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// releaseIndex is the release feed as persisted in the cache
+type releaseIndex struct {
+	// Fetched is the time the feed was read from the network
+	Fetched time.Time `json:"fetched"`
+	// Releases as read from the feed
+	Releases []release `json:"releases"`
+}
+
+const (
+	// releaseIndexFile is the name of the cached go.dev release feed
+	releaseIndexFile = "index.json"
+	// toolchainIndexFile is the name of the cached list of toolchain modules, kept apart from
+	// the release feed as both are used for offline mode
+	toolchainIndexFile = "toolchains.json"
+)
+
+// indexPath returns the location of the cached index name
+func (c *Cache) indexPath(name string) string {
+	return filepath.Join(c.dir, name)
+}
+
+// saveIndex persists releases as the last known index name
+func (c *Cache) saveIndex(name string, releases []release) error {
+	data, err := json.Marshal(releaseIndex{Fetched: time.Now().UTC(), Releases: releases})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, "index-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.indexPath(name))
+}
+
+// loadIndex reads the last known index name
+func (c *Cache) loadIndex(name string) (*releaseIndex, error) {
+	data, err := os.ReadFile(c.indexPath(name))
+	if err != nil {
+		return nil, err
+	}
+	var idx releaseIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("error decoding cached release index: %w", err)
+	}
+	return &idx, nil
+}
+
+// IndexAge returns the time the cached go.dev release index was fetched, fs.ErrNotExist is
+// returned if there is none
+func (c *Cache) IndexAge() (time.Time, error) {
+	idx, err := c.loadIndex(releaseIndexFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return idx.Fetched, nil
+}
+
+// queryCachedIndex reads all known go versions from the cached release index
+func (a *Application) queryCachedIndex() error {
+	if a.cache == nil {
+		return errors.New("no cache configured, cannot read cached release index")
+	}
+	name := releaseIndexFile
+	if a.goProxies != nil {
+		name = toolchainIndexFile
+	}
+	idx, err := a.cache.loadIndex(name)
+	if err != nil {
+		return err
+	}
+	a.logger.Warn("using cached release index, newer releases may be missing",
+		"fetched", idx.Fetched.Format(time.RFC3339),
+		"age", time.Since(idx.Fetched).Round(time.Minute).String())
+	for i := range idx.Releases {
+		if a.goProxies != nil {
+			a.processToolchainRelease(idx.Releases[i])
+			continue
+		}
+		a.processRelease(a.mirrors.first(), idx.Releases[i])
+	}
+	return nil
+}
+
+// queryOffline reads all known go versions from the cached release index. A missing index is
+// not an error, so installed versions can still be used without ever having been online
+func (a *Application) queryOffline() error {
+	err := a.queryCachedIndex()
+	if errors.Is(err, fs.ErrNotExist) {
+		a.logger.Warn("offline and no cached release index available, no versions known")
+		return nil
+	}
+	return err
+}
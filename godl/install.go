@@ -0,0 +1,406 @@
+package godl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// backupSuffix is appended to the staging directory name for the installation replaced
+	// by a forced download until the new one is in place
+	backupSuffix = ".old"
+	// staleStagingAge is the age after which staging directories of other versions are removed
+	staleStagingAge = 24 * time.Hour
+)
+
+// checkDirectory returns an error if dir is not an existing directory
+func checkDirectory(dir string) error {
+	i, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s does not exist", dir)
+	}
+	if err != nil {
+		return err
+	}
+	if !i.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return nil
+}
+
+// installDirectories calculates the download directory and the directory the version is saved to
+func (a *Application) installDirectories(version, dir string) (string, string, error) {
+	if err := a.cleanStaleStaging(dir, version); err != nil {
+		a.logger.Warn("could not clean up staging directories", "err", err, "path", dir)
+	}
+	downloadDestination := filepath.Join(dir, fmt.Sprintf("_%s", version))
+	saveDestination := filepath.Join(dir, version)
+
+	if _, err := os.Stat(downloadDestination); !errors.Is(err, fs.ErrNotExist) {
+		a.logger.Debug("download destination exists, resuming", "path", downloadDestination)
+	}
+	if _, err := os.Stat(saveDestination); !errors.Is(err, fs.ErrNotExist) && !a.forceDownload {
+		return "", saveDestination, fmt.Errorf("%w: %s", ErrAlreadyInstalled, saveDestination)
+	}
+	return downloadDestination, saveDestination, nil
+}
+
+// cleanStaleStaging restores installations left as backup by an interrupted swap and removes
+// leftover backups as well as staging directories of other versions than version that were
+// not touched for staleStagingAge
+func (a *Application) cleanStaleStaging(dir, version string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name, isStaging := strings.CutPrefix(e.Name(), "_")
+		if !e.IsDir() || !isStaging {
+			continue
+		}
+		name, isBackup := strings.CutSuffix(name, backupSuffix)
+		if v, err := Parse(name); err != nil || v.String() != name {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		if isBackup {
+			saveDestination := filepath.Join(dir, name)
+			if _, err := os.Stat(saveDestination); errors.Is(err, fs.ErrNotExist) {
+				a.logger.Warn("restoring installation from interrupted install", "path", saveDestination)
+				if err := os.Rename(p, saveDestination); err != nil {
+					return err
+				}
+				continue
+			}
+			a.logger.Debug("removing backup of previous installation", "path", p)
+			if err := os.RemoveAll(p); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == version {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+		if time.Since(fi.ModTime()) < staleStagingAge {
+			continue
+		}
+		if err := a.removeStaging(p, versionLockPath(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeStaging removes the staging directory p unless another process holds its version lock
+func (a *Application) removeStaging(p, lockPath string) error {
+	l, ok, err := tryLockFile(lockPath)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		a.logger.Debug("staging directory in use by another godl process", "path", p)
+		return nil
+	}
+	defer a.unlockWithWarning(l)
+	a.logger.Info("removing stale staging directory", "path", p)
+	return os.RemoveAll(p)
+}
+
+// Install downloads the newest version matching the version constraint (see ParseConstraint),
+// verifies and extracts it to dir/<resolved version> and returns the path of the installation.
+// If the version is already installed its path and ErrAlreadyInstalled are returned unless
+// WithForceDownload was used, the existing installation is kept if the download fails
+func (a *Application) Install(ctx context.Context, version, dir string) (string, error) {
+	goDownload, err := a.GetDownload(ctx, version)
+	if errors.Is(err, ErrVersionNotFound) && !a.forceDownload {
+		// without a matching release known, e.g. offline, an installed version still satisfies version
+		if installed, installedErr := ResolveInstalled(version, dir); installedErr == nil {
+			return filepath.Join(dir, installed.String()), fmt.Errorf("%w: %s", ErrAlreadyInstalled, filepath.Join(dir, installed.String()))
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("error selecting download: %w", err)
+	}
+	if goDownload.Version.String() != version {
+		a.logger.Info("resolved version", "constraint", version, "version", goDownload.Version)
+	}
+	if err := checkDirectory(dir); err != nil {
+		return "", err
+	}
+
+	versionLock, err := a.lockVersion(ctx, dir, goDownload.Version.String())
+	if err != nil {
+		return "", err
+	}
+	defer a.unlockWithWarning(versionLock)
+	destinationLock, err := a.lockDestination(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+	downloadDestination, saveDestination, err := a.installDirectories(goDownload.Version.String(), dir)
+	a.unlockWithWarning(destinationLock)
+	if err != nil {
+		return saveDestination, err
+	}
+	a.logger.Debug("Starting download", "destination", downloadDestination)
+	err = a.downloadGoVersion(ctx, goDownload, downloadDestination, saveDestination)
+	if err != nil {
+		if removeErr := a.cleanupDownloadDestination(downloadDestination); removeErr != nil {
+			a.logger.Warn("could not clean up download destination", "err", removeErr, "path", downloadDestination)
+		}
+		return "", err
+	}
+	a.logger.Debug("download done")
+	return saveDestination, nil
+}
+
+// cleanupDownloadDestination removes everything but partial downloads from the download
+// destination, so a later attempt can resume them. The directory is removed if nothing is left
+func (a *Application) cleanupDownloadDestination(downloadDestination string) error {
+	entries, err := os.ReadDir(downloadDestination)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	keep := false
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), partialSuffix) {
+			keep = true
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(downloadDestination, e.Name())); err != nil {
+			return err
+		}
+	}
+	if keep {
+		a.logger.Info("keeping partial download for next attempt", "path", downloadDestination)
+		return nil
+	}
+	return os.Remove(downloadDestination)
+}
+
+// downloadGoVersion will download selected go version, an existing installation is only
+// replaced after the new version was downloaded, verified and extracted
+func (a *Application) downloadGoVersion(ctx context.Context, goDownload *Download, downloadDestination, saveDestination string) error {
+	err := os.MkdirAll(downloadDestination, 0700)
+	if err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	// an interrupted run may have left an incomplete extraction behind
+	if err := os.RemoveAll(filepath.Join(downloadDestination, "go")); err != nil {
+		return fmt.Errorf("error removing previous extraction: %w", err)
+	}
+	downloadFileName, cacheLock, err := a.archive(ctx, goDownload, downloadDestination)
+	if err != nil {
+		return err
+	}
+	err = a.extract(ctx, goDownload, downloadFileName, downloadDestination)
+	if cacheLock != nil {
+		a.unlockWithWarning(cacheLock)
+	}
+	if err != nil {
+		return fmt.Errorf("error extracting downloaded archive: %w", err)
+	}
+
+	goDirectory := filepath.Join(downloadDestination, "go")
+	if _, err := os.Stat(goDirectory); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s expected but not found", ErrArchiveCorrupt, goDirectory)
+	}
+
+	destinationLock, err := a.lockDestination(ctx, filepath.Dir(saveDestination))
+	if err != nil {
+		return err
+	}
+	err = a.swap(goDirectory, saveDestination, downloadDestination+backupSuffix)
+	a.unlockWithWarning(destinationLock)
+	if err != nil {
+		return err
+	}
+	// the extracted directory carries the release date, record the installation date instead
+	now := time.Now()
+	if err := os.Chtimes(saveDestination, now, now); err != nil {
+		a.logger.Warn("could not set installation time", "err", err, "path", saveDestination)
+	}
+
+	err = os.RemoveAll(downloadDestination)
+	if err != nil {
+		return fmt.Errorf("could not remove download destination: %w", err)
+	}
+	return nil
+}
+
+// swap moves the extracted goDirectory to saveDestination. An existing installation is moved
+// to backup first and restored if the new installation cannot be moved in place
+func (a *Application) swap(goDirectory, saveDestination, backup string) error {
+	_, err := os.Stat(saveDestination)
+	if errors.Is(err, fs.ErrNotExist) {
+		if err := os.Rename(goDirectory, saveDestination); err != nil {
+			return fmt.Errorf("could not move go directory: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(backup); err != nil {
+		return fmt.Errorf("could not remove previous backup: %w", err)
+	}
+	if err := os.Rename(saveDestination, backup); err != nil {
+		return fmt.Errorf("could not move existing installation aside: %w", err)
+	}
+	if err := os.Rename(goDirectory, saveDestination); err != nil {
+		if restoreErr := os.Rename(backup, saveDestination); restoreErr != nil {
+			return fmt.Errorf("could not move go directory: %w, restoring %s failed: %w", err, saveDestination, restoreErr)
+		}
+		return fmt.Errorf("could not move go directory, kept existing installation: %w", err)
+	}
+	if err := os.RemoveAll(backup); err != nil {
+		a.logger.Warn("could not remove previous installation", "err", err, "path", backup)
+	}
+	return nil
+}
+
+// archive returns the path of the verified archive of d, taken from the cache if
+// possible. Without a cache the archive is downloaded to downloadDestination, toolchain
+// modules are always downloaded to downloadDestination. The lock of a cached archive is
+// returned held, so it is kept until the caller has extracted it
+func (a *Application) archive(ctx context.Context, d *Download, downloadDestination string) (string, *fileLock, error) {
+	if d.Kind == moduleKind {
+		p, err := a.toolchainArchive(ctx, d, downloadDestination)
+		return p, nil, err
+	}
+	downloadFileName := filepath.Join(downloadDestination, d.FileName)
+	var cacheLock *fileLock
+	if a.cache != nil {
+		p, err := a.cache.Path(d)
+		if err != nil {
+			return "", nil, err
+		}
+		// the cache may be shared by processes installing into other destinations
+		cacheLock, err = a.lockCacheEntry(ctx, p)
+		if err != nil {
+			return "", nil, err
+		}
+		if p, ok := a.cache.Lookup(d); ok {
+			a.logger.Debug("using cached archive", "path", p)
+			return p, cacheLock, nil
+		}
+		downloadFileName = p
+	}
+	err := a.fetchArchive(ctx, d, downloadFileName)
+	if err != nil && cacheLock != nil {
+		a.unlockWithWarning(cacheLock)
+		cacheLock = nil
+	}
+	return downloadFileName, cacheLock, err
+}
+
+// fetchArchive downloads the archive of d to downloadFileName
+func (a *Application) fetchArchive(ctx context.Context, d *Download, downloadFileName string) error {
+	if a.offline {
+		return fmt.Errorf("%w: offline and %s is not in the archive cache", ErrNetwork, d.FileName)
+	}
+	if err := d.DownloadGoArchiveToFile(ctx, downloadFileName); err != nil {
+		return fmt.Errorf("error downloading: %w", err)
+	}
+	return nil
+}
+
+// extract unpacks the downloaded archive of d into dst depending on its type
+func (a *Application) extract(ctx context.Context, d *Download, archiveFile, dst string) error {
+	switch {
+	case d.Kind == moduleKind:
+		return a.extractToolchain(ctx, d, archiveFile, dst)
+	case strings.HasSuffix(archiveFile, ".tar.gz"):
+		f, err := os.Open(archiveFile)
+		if err != nil {
+			return fmt.Errorf("error opening downloaded archive: %w", err)
+		}
+		defer func() {
+			err := f.Close()
+			if err != nil {
+				a.logger.Warn("error closing tar archive", "err", err)
+			}
+		}()
+		return a.Untar(ctx, dst, f)
+	case strings.HasSuffix(archiveFile, ".zip"):
+		return a.Unzip(ctx, archiveFile, dst)
+	}
+	return fmt.Errorf("%w: unsupported archive %s", ErrArchiveCorrupt, archiveFile)
+}
+
+// Use links the newest version installed in dir matching the version constraint as
+// linkName, a relative linkName is created within dir. PreviousVersion ("-") links the
+// version linked before. The link is replaced atomically, an existing file or directory
+// at its place is only replaced with WithForceLink
+func (a *Application) Use(version, dir, linkName string) error {
+	if version == "" {
+		return errors.New("no version provided")
+	}
+	if dir == "" {
+		return errors.New("no destination provided")
+	}
+	// link targets are resolved from the directory of the link, not the working directory
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	linkPath := CreateSymlinkPath(dir, linkName)
+
+	destinationLock, err := a.lockDestination(context.Background(), dir)
+	if err != nil {
+		return err
+	}
+	defer a.unlockWithWarning(destinationLock)
+	state, err := loadLinkState(linkPath)
+	if err != nil {
+		return err
+	}
+
+	var saveDestination string
+	switch version {
+	case PreviousVersion:
+		if state.Previous == "" {
+			return fmt.Errorf("%w: no previous version linked as %s", ErrNotInstalled, linkPath)
+		}
+		if _, err := os.Stat(state.Previous); err != nil {
+			return fmt.Errorf("%w: previous version %s", ErrNotInstalled, state.Previous)
+		}
+		saveDestination = state.Previous
+		a.logger.Info("switching to previous version", "version", filepath.Base(saveDestination))
+	default:
+		saveDestination = filepath.Join(dir, version)
+		if _, err := os.Stat(saveDestination); errors.Is(err, fs.ErrNotExist) {
+			installed, err := ResolveInstalled(version, dir)
+			if err != nil {
+				return err
+			}
+			a.logger.Info("resolved version", "constraint", version, "version", installed)
+			saveDestination = filepath.Join(dir, installed.String())
+		}
+	}
+
+	if err := link(saveDestination, linkPath, a.forceLink); err != nil {
+		return err
+	}
+	if state.Current != saveDestination {
+		state.Previous, state.Current = state.Current, saveDestination
+	}
+	if err := saveLinkState(linkPath, state); err != nil {
+		a.logger.Warn("could not record linked version", "err", err, "path", linkStatePath(linkPath))
+	}
+	return nil
+}
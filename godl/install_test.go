@@ -0,0 +1,419 @@
+package godl
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newInstallTestServer serves a feed with a single release and its archive, archive
+// downloads are counted in archiveRequests if not nil
+func newInstallTestServer(t *testing.T, archiveRequests *int) *httptest.Server {
+	archive := buildTarGz(t, []tar.Header{
+		{Name: "go/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "go/VERSION", Typeflag: tar.TypeReg, Mode: 0644},
+	}, map[string]string{"go/VERSION": "go1.22.1"}).Bytes()
+	sum := sha256.Sum256(archive)
+	fileName := fmt.Sprintf("go1.22.1.%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	feed := fmt.Sprintf(`[{"version": "go1.22.1", "stable": true, "files": [
+		{"filename": %q, "os": %q, "arch": %q, "version": "go1.22.1", "sha256": %q, "size": %d, "kind": "archive"}]}]`,
+		fileName, runtime.GOOS, runtime.GOARCH, hex.EncodeToString(sum[:]), len(archive))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("mode") == "json":
+			_, _ = w.Write([]byte(feed))
+		case r.URL.Path == "/dl/"+fileName:
+			if archiveRequests != nil {
+				*archiveRequests++
+			}
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestInstall(t *testing.T) {
+	srv := newInstallTestServer(t, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p, err := a.Install(context.Background(), "1.22.1", dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p != filepath.Join(dir, "1.22.1") {
+		t.Errorf("unexpected installation path %s", p)
+	}
+	if _, err := os.Stat(filepath.Join(p, "VERSION")); err != nil {
+		t.Errorf("expected VERSION in installation: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "_1.22.1")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected download directory to be removed")
+	}
+
+	if _, err := a.Install(context.Background(), "1.22.1", dir); !errors.Is(err, ErrAlreadyInstalled) {
+		t.Errorf("expected ErrAlreadyInstalled, got %v", err)
+	}
+
+	if err := a.Use("1.22.1", dir, "current"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "current", "VERSION")); err != nil {
+		t.Errorf("expected VERSION in linked installation: %s", err)
+	}
+	if err := a.Use("1.21.0", dir, "current"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("expected ErrNotInstalled, got %v", err)
+	}
+}
+
+func TestInstallCancelled(t *testing.T) {
+	srv := newInstallTestServer(t, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithHTTPClient(srv.Client()), WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := a.Install(ctx, "1.22.1", dir); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "_1.22.1")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected download directory to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1.22.1")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no installation")
+	}
+}
+
+func TestInstallProgress(t *testing.T) {
+	srv := newInstallTestServer(t, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	var reports []Progress
+
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()), WithProgress(func(p Progress) {
+		reports = append(reports, p)
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", t.TempDir()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var done []Progress
+	for _, p := range reports {
+		if p.Done {
+			done = append(done, p)
+		}
+	}
+	size := a.Downloads[0].Size
+	expected := []Progress{
+		{Phase: PhaseDownload, FileName: a.Downloads[0].FileName, Bytes: size, Total: size, Done: true},
+		{Phase: PhaseExtract, FileName: a.Downloads[0].FileName, Bytes: size, Total: size, Files: 1, Done: true},
+	}
+	if diff := cmp.Diff(expected, done); diff != "" {
+		t.Errorf("mismatch in expectation: \n\n%s", diff)
+	}
+}
+
+func TestInstallPlatform(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"go/VERSION": "go1.22.1", "go/bin/go.exe": "MZ"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+	sum := sha256.Sum256(archive)
+	feed := fmt.Sprintf(`[{"version": "go1.22.1", "stable": true, "files": [
+		{"filename": "go1.22.1.windows-amd64.zip", "os": "windows", "arch": "amd64", "version": "go1.22.1", "sha256": %q, "size": %d, "kind": "archive"}]}]`,
+		hex.EncodeToString(sum[:]), len(archive))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("mode") == "json":
+			_, _ = w.Write([]byte(feed))
+		case r.URL.Path == "/dl/go1.22.1.windows-amd64.zip":
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()), WithPlatform("windows", "amd64"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p, err := a.Install(context.Background(), "1.22", dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(p, "bin", "go.exe")); err != nil {
+		t.Errorf("expected bin/go.exe in installation: %s", err)
+	}
+}
+
+func TestInstallSharedCache(t *testing.T) {
+	var archiveRequests int
+	srv := newInstallTestServer(t, &archiveRequests)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cacheDir := t.TempDir()
+	newApp := func(timeout time.Duration) *Application {
+		a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(cacheDir), WithLockTimeout(timeout))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return a
+	}
+
+	// the cached archive is locked by another process
+	a := newApp(50 * time.Millisecond)
+	p, err := a.cache.Path(&a.Downloads[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		t.Fatal(err)
+	}
+	l, ok, err := tryLockFile(p + lockSuffix)
+	if err != nil || !ok {
+		t.Fatalf("could not acquire lock: %t, %v", ok, err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", t.TempDir()); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// concurrent installs into different destinations download the archive once
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		a := newApp(5 * time.Second)
+		wg.Go(func() {
+			_, errs[i] = a.Install(context.Background(), "1.22.1", t.TempDir())
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	}
+	if archiveRequests != 1 {
+		t.Errorf("expected a single download, got %d", archiveRequests)
+	}
+
+	// the archive stays locked until it is extracted
+	var extracting, locked bool
+	a, err = NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(cacheDir), WithForceDownload(), WithProgress(func(progress Progress) {
+		if progress.Phase != PhaseExtract || extracting {
+			return
+		}
+		extracting = true
+		l, ok, err := tryLockFile(p + lockSuffix)
+		locked = err == nil && !ok
+		if ok {
+			_ = l.Unlock()
+		}
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", t.TempDir()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !extracting || !locked {
+		t.Errorf("expected archive to be locked during extraction, extracting %t, locked %t", extracting, locked)
+	}
+
+	// cleaning the cache waits for the lock of the archive
+	l, ok, err = tryLockFile(p + lockSuffix)
+	if err != nil || !ok {
+		t.Fatalf("could not acquire lock: %t, %v", ok, err)
+	}
+	if err := a.cache.Clean(context.Background(), 50*time.Millisecond); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+	if _, err := os.Stat(p); err != nil {
+		t.Errorf("expected locked archive to be kept: %v", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.cache.Clean(context.Background(), 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, "archives")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected archives to be removed, got %v", err)
+	}
+}
+
+func TestPlatformArm(t *testing.T) {
+	feed := `[{"version": "go1.22.1", "stable": true, "files": [
+		{"filename": "go1.22.1.linux-armv6l.tar.gz", "os": "linux", "arch": "armv6l", "version": "go1.22.1", "sha256": "00", "size": 1, "kind": "archive"}]}]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(feed))
+	}))
+	t.Cleanup(srv.Close)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, goarch := range []string{"arm", "armv6l"} {
+		t.Run(goarch, func(t *testing.T) {
+			a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()), WithPlatform("linux", goarch))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(a.Downloads) != 1 || a.Downloads[0].GoArch != "armv6l" {
+				t.Errorf("expected the armv6l release, got %v", a.Downloads)
+			}
+		})
+	}
+}
+
+func TestInstallForceRollback(t *testing.T) {
+	srv := newInstallTestServer(t, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", dir); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// a forced download failing verification keeps the existing installation
+	forced, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()), WithForceDownload())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	forced.Downloads[0].Sha256 = strings.Repeat("0", 64)
+	if _, err := forced.Install(context.Background(), "1.22.1", dir); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1.22.1", "VERSION")); err != nil {
+		t.Errorf("expected existing installation to be kept: %s", err)
+	}
+
+	// a successful forced download replaces the installation
+	if err := os.WriteFile(filepath.Join(dir, "1.22.1", "marker"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	forced.Downloads = nil
+	if _, err := forced.Install(context.Background(), "1.22.1", dir); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1.22.1", "marker")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected installation to be replaced")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		// lock files are kept
+		if e.IsDir() && e.Name() != "1.22.1" {
+			t.Errorf("expected only the installation to be left, got %s", e.Name())
+		}
+	}
+}
+
+func TestInstallStaleStaging(t *testing.T) {
+	srv := newInstallTestServer(t, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+
+	// an interrupted swap left the installation as backup only
+	if err := os.MkdirAll(filepath.Join(dir, "_1.22.1"+backupSuffix), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// an abandoned install of another version
+	stale := filepath.Join(dir, "_1.21.0")
+	if err := os.MkdirAll(stale, 0700); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * staleStagingAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", dir); !errors.Is(err, ErrAlreadyInstalled) {
+		t.Errorf("expected restored installation, got %v", err)
+	}
+	for _, name := range []string{"_1.22.1" + backupSuffix, "_1.21.0"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected %s to be removed", name)
+		}
+	}
+}
+
+func TestInstallLocked(t *testing.T) {
+	srv := newInstallTestServer(t, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	dir := t.TempDir()
+
+	l, ok, err := tryLockFile(versionLockPath(dir, "1.22.1"))
+	if err != nil || !ok {
+		t.Fatalf("could not acquire lock: %t, %v", ok, err)
+	}
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()), WithLockTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", dir); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected ErrLocked, got %v", err)
+	}
+
+	// the lock is released while waiting
+	time.AfterFunc(50*time.Millisecond, func() { _ = l.Unlock() })
+	a, err = NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()), WithLockTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", dir); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,199 @@
+package godl
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Installation is a go version installed in a destination directory
+type Installation struct {
+	// Version installed
+	Version Version
+	// Path of installation
+	Path string
+	// Size on disk in bytes
+	Size int64
+	// InstalledAt is the time the version was installed
+	InstalledAt time.Time
+	// Current is true if the link points at this installation
+	Current bool
+}
+
+// currentTarget returns the resolved target of the link linkName in dir, empty if there is none
+func currentTarget(dir, linkName string) string {
+	if linkName == "" {
+		return ""
+	}
+	target, err := resolvePath(CreateSymlinkPath(dir, linkName))
+	if err != nil {
+		return ""
+	}
+	return target
+}
+
+// isCurrent returns true if path is the target of the link
+func isCurrent(path, target string) bool {
+	if target == "" {
+		return false
+	}
+	resolved, err := resolvePath(path)
+	return err == nil && resolved == target
+}
+
+// resolvePath returns the absolute path of p with all symbolic links resolved, so paths
+// relative to the working directory compare equal to link targets
+func resolvePath(p string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(resolved)
+}
+
+// ListInstalled returns all versions installed in dir, newest first. Current is set for the
+// installation the link linkName points at
+func ListInstalled(dir, linkName string) ([]Installation, error) {
+	versions, err := installedVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+	target := currentTarget(dir, linkName)
+	result := make([]Installation, 0, len(versions))
+	for _, v := range versions {
+		p := filepath.Join(dir, v.String())
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		size, err := dirSize(p)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Installation{
+			Version:     v,
+			Path:        p,
+			Size:        size,
+			InstalledAt: fi.ModTime(),
+			Current:     isCurrent(p, target),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version.Compare(result[j].Version) > 0
+	})
+	return result, nil
+}
+
+// tryLockDestination locks dir against changes by other processes, ErrLocked is returned if
+// another process holds the lock
+func tryLockDestination(dir string) (*fileLock, error) {
+	return tryLockPath(filepath.Join(dir, destinationLockName))
+}
+
+// tryLockPath acquires the lock on p, ErrLocked is returned if another process holds it
+func tryLockPath(p string) (*fileLock, error) {
+	l, ok, err := tryLockFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is held by another godl process", ErrLocked, p)
+	}
+	return l, nil
+}
+
+// removeVersion removes installation from dir while holding its version lock, so it is not
+// removed while another process installs it, the lock file is deleted afterwards. The link
+// linkName is removed first unless empty. ErrLocked is returned if another process installs it
+func removeVersion(dir string, installation Installation, linkName string) error {
+	l, err := tryLockPath(versionLockPath(dir, installation.Version.String()))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Remove() }()
+	if linkName != "" {
+		if err := os.Remove(CreateSymlinkPath(dir, linkName)); err != nil {
+			return fmt.Errorf("could not remove symbolic link: %w", err)
+		}
+	}
+	if err := os.RemoveAll(installation.Path); err != nil {
+		return fmt.Errorf("could not remove %s: %w", installation.Path, err)
+	}
+	return nil
+}
+
+// RemoveInstalled removes version from dir. The version the link linkName points at is only
+// removed if force is set, ErrVersionInUse is returned otherwise. When forced the link is
+// removed as well. ErrLocked is returned if another process is changing dir or installing version
+func RemoveInstalled(version, dir, linkName string, force bool) (*Installation, error) {
+	v, err := Parse(version)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDirectory(dir); err != nil {
+		return nil, err
+	}
+	l, err := tryLockDestination(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = l.Unlock() }()
+	installations, err := ListInstalled(dir, linkName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range installations {
+		if installations[i].Version != v {
+			continue
+		}
+		if installations[i].Current && !force {
+			return nil, fmt.Errorf("%w: %s is linked as %s", ErrVersionInUse, installations[i].Path, linkName)
+		}
+		var link string
+		if installations[i].Current {
+			link = linkName
+		}
+		if err := removeVersion(dir, installations[i], link); err != nil {
+			return nil, err
+		}
+		return &installations[i], nil
+	}
+	return nil, fmt.Errorf("%w: %s in %s", ErrNotInstalled, version, dir)
+}
+
+// PruneInstalled removes old versions from dir and returns the removed installations. The
+// newest keep versions and the version the link linkName points at are never removed. If
+// olderThan is not zero only versions installed more than olderThan ago are removed. ErrLocked
+// is returned if another process is changing dir or installing a version to be removed
+func PruneInstalled(dir, linkName string, keep int, olderThan time.Duration) ([]Installation, error) {
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	l, err := tryLockDestination(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = l.Unlock() }()
+	installations, err := ListInstalled(dir, linkName)
+	if err != nil {
+		return nil, err
+	}
+	var removed []Installation
+	for i, installation := range installations {
+		if i < keep || installation.Current {
+			continue
+		}
+		if olderThan > 0 && time.Since(installation.InstalledAt) < olderThan {
+			continue
+		}
+		if err := removeVersion(dir, installation, ""); err != nil {
+			return removed, err
+		}
+		removed = append(removed, installation)
+	}
+	return removed, nil
+}
@@ -0,0 +1,174 @@
+package godl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newInstalledDir creates fake installations of versions in a temporary directory, the
+// installation time of the n-th version is n days ago. current is linked if not empty
+func newInstalledDir(t *testing.T, current string, versions ...string) string {
+	dir := t.TempDir()
+	for i, v := range versions {
+		p := filepath.Join(dir, v)
+		if err := os.MkdirAll(filepath.Join(p, "bin"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(p, "VERSION"), []byte("go"+v), 0600); err != nil {
+			t.Fatal(err)
+		}
+		installed := time.Now().Add(-time.Duration(i) * 24 * time.Hour)
+		if err := os.Chtimes(p, installed, installed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// staging directories and files are not installations
+	if err := os.MkdirAll(filepath.Join(dir, "_1.23.0"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if current != "" {
+		if runtime.GOOS == "windows" {
+			t.Skip("links are copies on windows")
+		}
+		if err := Link(filepath.Join(dir, current), CreateSymlinkPath(dir, "current")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// installedNames returns the versions installed in dir
+func installedNames(t *testing.T, dir string) []string {
+	installations, err := ListInstalled(dir, "current")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var result []string
+	for _, i := range installations {
+		result = append(result, i.Version.String())
+	}
+	return result
+}
+
+func TestListInstalled(t *testing.T) {
+	dir := newInstalledDir(t, "1.21.5", "1.20.3", "1.22.1", "1.21.5")
+	installations, err := ListInstalled(dir, "current")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff([]string{"1.22.1", "1.21.5", "1.20.3"}, installedNames(t, dir)); diff != "" {
+		t.Errorf("mismatch in expectation: \n\n%s", diff)
+	}
+	for _, i := range installations {
+		if i.Current != (i.Version.String() == "1.21.5") {
+			t.Errorf("unexpected current %t for %s", i.Current, i.Version)
+		}
+		if i.Size != int64(len("go"+i.Version.String())) {
+			t.Errorf("unexpected size %d for %s", i.Size, i.Version)
+		}
+	}
+}
+
+func TestRemoveInstalled(t *testing.T) {
+	dir := newInstalledDir(t, "1.21.5", "1.22.1", "1.21.5")
+
+	if _, err := RemoveInstalled("1.21.5", dir, "current", false); !errors.Is(err, ErrVersionInUse) {
+		t.Errorf("expected ErrVersionInUse, got %v", err)
+	}
+	if _, err := RemoveInstalled("1.19.1", dir, "current", false); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("expected ErrNotInstalled, got %v", err)
+	}
+	if _, err := RemoveInstalled("go1.22.1", dir, "current", false); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := RemoveInstalled("1.21.5", dir, "current", true); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if names := installedNames(t, dir); len(names) != 0 {
+		t.Errorf("expected no installations, got %v", names)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "current")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected link to be removed, got %v", err)
+	}
+}
+
+var testCasesPruneInstalled = []struct {
+	name      string
+	keep      int
+	olderThan time.Duration
+	expected  []string
+}{
+	{
+		name:     "keep",
+		keep:     2,
+		expected: []string{"1.22.1", "1.21.5", "1.19.1"},
+	},
+	{
+		name:      "older-than",
+		olderThan: 36 * time.Hour,
+		expected:  []string{"1.21.5", "1.20.3", "1.19.1"},
+	},
+	{
+		name:      "keep-and-older-than",
+		keep:      1,
+		olderThan: 12 * time.Hour,
+		expected:  []string{"1.22.1", "1.21.5", "1.19.1"},
+	},
+}
+
+func TestPruneInstalled(t *testing.T) {
+	for _, tc := range testCasesPruneInstalled {
+		t.Run(tc.name, func(t *testing.T) {
+			// installed today, yesterday, two and three days ago
+			dir := newInstalledDir(t, "1.19.1", "1.21.5", "1.20.3", "1.22.1", "1.19.1")
+			if _, err := PruneInstalled(dir, "current", tc.keep, tc.olderThan); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(tc.expected, installedNames(t, dir)); diff != "" {
+				t.Errorf("mismatch in expectation: \n\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRemoveInstalledLocked(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		lock string
+	}{
+		{name: "destination", lock: destinationLockName},
+		{name: "version", lock: "_1.22.1.lock"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := newInstalledDir(t, "", "1.22.1")
+			l, ok, err := tryLockFile(filepath.Join(dir, tc.lock))
+			if err != nil || !ok {
+				t.Fatalf("could not acquire lock: %t, %v", ok, err)
+			}
+			if _, err := RemoveInstalled("1.22.1", dir, "current", false); !errors.Is(err, ErrLocked) {
+				t.Errorf("expected ErrLocked, got %v", err)
+			}
+			if _, err := PruneInstalled(dir, "current", 0, 0); !errors.Is(err, ErrLocked) {
+				t.Errorf("expected ErrLocked, got %v", err)
+			}
+			if diff := cmp.Diff([]string{"1.22.1"}, installedNames(t, dir)); diff != "" {
+				t.Errorf("mismatch in expectation: \n\n%s", diff)
+			}
+			if err := l.Unlock(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := RemoveInstalled("1.22.1", dir, "current", false); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if _, err := os.Stat(versionLockPath(dir, "1.22.1")); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("expected version lock to be removed, got %v", err)
+			}
+		})
+	}
+}
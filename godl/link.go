@@ -0,0 +1,70 @@
+//go:build !windows
+
+package godl
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// CreateSymlinkPath returns simply the place where the symbolic link will be created
+func CreateSymlinkPath(destinationDir, symbolicLinkname string) string {
+	if strings.HasPrefix(symbolicLinkname, "/") {
+		return symbolicLinkname
+	}
+	return path.Join(destinationDir, symbolicLinkname)
+}
+
+// Link will atomically point the symbolic link dst at src, ErrLinkExists is returned if
+// dst exists and is not a symbolic link
+func Link(src, dst string) error {
+	return link(src, dst, false)
+}
+
+// link creates the symbolic link under a temporary name and renames it over dst, so dst
+// always exists. An existing file or directory at dst is only replaced if force is set
+func link(src, dst string, force bool) error {
+	var aside string
+	fi, err := os.Lstat(dst)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("could not check if destination is a symbolic link: %w", err)
+	case fi.Mode()&os.ModeSymlink == 0 && !force:
+		return fmt.Errorf("%w: %s", ErrLinkExists, dst)
+	case fi.Mode()&os.ModeSymlink == 0:
+		// a symbolic link cannot be renamed over a directory, move it aside first
+		aside = fmt.Sprintf("%s.%d.old", dst, os.Getpid())
+		if err := os.Rename(dst, aside); err != nil {
+			return fmt.Errorf("could not move %s aside: %w", dst, err)
+		}
+	}
+
+	tmp := fmt.Sprintf("%s.%d.tmp", dst, os.Getpid())
+	_ = os.Remove(tmp)
+	if err := os.Symlink(src, tmp); err != nil {
+		return errors.Join(fmt.Errorf("could not create symbolic link: %w", err), restore(aside, dst))
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = os.Remove(tmp)
+		return errors.Join(fmt.Errorf("could not replace symbolic link: %w", err), restore(aside, dst))
+	}
+	if aside != "" {
+		if err := os.RemoveAll(aside); err != nil {
+			return fmt.Errorf("could not remove replaced %s: %w", aside, err)
+		}
+	}
+	return nil
+}
+
+// restore moves aside back to dst if not empty
+func restore(aside, dst string) error {
+	if aside == "" {
+		return nil
+	}
+	return os.Rename(aside, dst)
+}
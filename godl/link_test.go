@@ -0,0 +1,120 @@
+//go:build !windows
+
+package godl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// linkTarget returns the installation the link current in dir points at
+func linkTarget(t *testing.T, dir string) string {
+	target, err := os.Readlink(filepath.Join(dir, "current"))
+	if err != nil {
+		t.Fatalf("could not read link: %s", err)
+	}
+	return filepath.Base(target)
+}
+
+func TestUseSwitch(t *testing.T) {
+	dir := newInstalledDir(t, "", "1.22.1", "1.21.5")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithLocalOnly())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := a.Use(PreviousVersion, dir, "current"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("expected ErrNotInstalled without previous version, got %v", err)
+	}
+	for _, step := range []struct {
+		version  string
+		expected string
+	}{
+		{version: "1.22", expected: "1.22.1"},
+		{version: "1.21.5", expected: "1.21.5"},
+		{version: PreviousVersion, expected: "1.22.1"},
+		{version: PreviousVersion, expected: "1.21.5"},
+		{version: "1.21.5", expected: "1.21.5"},
+		{version: PreviousVersion, expected: "1.22.1"},
+	} {
+		if err := a.Use(step.version, dir, "current"); err != nil {
+			t.Fatalf("unexpected error using %s: %s", step.version, err)
+		}
+		if target := linkTarget(t, dir); target != step.expected {
+			t.Errorf("expected %s after using %s, got %s", step.expected, step.version, target)
+		}
+	}
+}
+
+func TestUseExistingDirectory(t *testing.T) {
+	dir := newInstalledDir(t, "", "1.22.1")
+	if err := os.MkdirAll(filepath.Join(dir, "current", "bin"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithLocalOnly())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := a.Use("1.22.1", dir, "current"); !errors.Is(err, ErrLinkExists) {
+		t.Errorf("expected ErrLinkExists, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "current", "bin")); err != nil {
+		t.Errorf("expected directory to be kept: %s", err)
+	}
+
+	a, err = NewApplication(context.Background(), WithLogger(logger), WithLocalOnly(), WithForceLink())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := a.Use("1.22.1", dir, "current"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if target := linkTarget(t, dir); target != "1.22.1" {
+		t.Errorf("expected link to 1.22.1, got %s", target)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "1.22.1" && e.Name() != "_1.23.0" {
+			t.Errorf("unexpected leftover %s", e.Name())
+		}
+	}
+}
+
+func TestUseRelativeDestination(t *testing.T) {
+	dir := newInstalledDir(t, "", "1.22.1", "1.21.5")
+	t.Chdir(filepath.Dir(dir))
+	rel := filepath.Base(dir)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithLocalOnly())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := a.Use("1.22.1", rel, "current"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "current", "VERSION")); err != nil {
+		t.Errorf("expected link to resolve: %s", err)
+	}
+	if err := a.Use("1.21.5", rel, "current"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := a.Use(PreviousVersion, rel, "current"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if target := linkTarget(t, dir); target != "1.22.1" {
+		t.Errorf("expected link to 1.22.1, got %s", target)
+	}
+	if _, err := RemoveInstalled("1.22.1", rel, "current", false); !errors.Is(err, ErrVersionInUse) {
+		t.Errorf("expected ErrVersionInUse, got %v", err)
+	}
+}
@@ -1,6 +1,6 @@
 //go:build windows
 
-package internal
+package godl
 
 import (
 	"errors"
@@ -17,18 +17,37 @@ func CreateSymlinkPath(destinationDir, symbolicLinkname string) string {
 	return path.Join(destinationDir, symbolicLinkname)
 }
 
-// Link will create a symbolic link from src to dst
+// Link will copy src to dst, the copy is created under a temporary name and replaces dst
+// when complete
 func Link(src, dst string) error {
-	if _, err := os.Stat(dst); errors.Is(err, fs.ErrNotExist) {
-		return copyDirectory(src, dst)
+	return link(src, dst, true)
+}
+
+// link copies src to a temporary directory and swaps it with dst. Links are copies on
+// Windows, so dst is always replaced
+func link(src, dst string, _ bool) error {
+	tmp := fmt.Sprintf("%s.%d.tmp", dst, os.Getpid())
+	if err := os.RemoveAll(tmp); err != nil {
+		return fmt.Errorf("could not remove temporary copy: %w", err)
+	}
+	if err := copyDirectory(src, tmp); err != nil {
+		_ = os.RemoveAll(tmp)
+		return err
 	}
 
-	err := os.RemoveAll(dst)
-	if err != nil {
+	if _, err := os.Stat(dst); errors.Is(err, fs.ErrNotExist) {
+		return os.Rename(tmp, dst)
+	}
+	aside := fmt.Sprintf("%s.%d.old", dst, os.Getpid())
+	if err := os.Rename(dst, aside); err != nil {
+		_ = os.RemoveAll(tmp)
 		return fmt.Errorf("could not remove destination: %w", err)
 	}
-
-	return copyDirectory(src, dst)
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = os.RemoveAll(tmp)
+		return errors.Join(fmt.Errorf("could not replace destination: %w", err), os.Rename(aside, dst))
+	}
+	return os.RemoveAll(aside)
 }
 
 // copyDirectory will just copy the entire dir structure, expects the target to not exist (should
@@ -0,0 +1,58 @@
+package godl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// PreviousVersion selects the version linked before the current one in Use
+const PreviousVersion = "-"
+
+// linkState records the targets of a link, stored next to the link
+type linkState struct {
+	// Current is the installation the link points at
+	Current string `json:"current"`
+	// Previous is the installation the link pointed at before
+	Previous string `json:"previous,omitempty"`
+}
+
+// linkStatePath returns the file the state of the link at linkPath is stored in
+func linkStatePath(linkPath string) string {
+	return filepath.Join(filepath.Dir(linkPath), fmt.Sprintf(".%s.godl.json", filepath.Base(linkPath)))
+}
+
+// loadLinkState returns the recorded state of the link at linkPath. Links created without state
+// are read using os.Readlink
+func loadLinkState(linkPath string) (linkState, error) {
+	var state linkState
+	data, err := os.ReadFile(linkStatePath(linkPath))
+	if errors.Is(err, fs.ErrNotExist) {
+		state.Current, _ = os.Readlink(linkPath)
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("error reading %s: %w", linkStatePath(linkPath), err)
+	}
+	return state, nil
+}
+
+// saveLinkState stores state for the link at linkPath
+func saveLinkState(linkPath string, state linkState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	p := linkStatePath(linkPath)
+	tmp := fmt.Sprintf("%s.%d.tmp", p, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
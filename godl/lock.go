@@ -0,0 +1,139 @@
+package godl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// destinationLockName is the lock file guarding changes to a destination directory
+	destinationLockName = ".godl.lock"
+	// lockSuffix is appended to the path of a cached archive for the lock guarding it
+	lockSuffix = ".lock"
+	// lockPollInterval is the interval a held lock is retried in
+	lockPollInterval = 100 * time.Millisecond
+)
+
+// DefaultLockTimeout is the time to wait for a lock held by another process unless
+// WithLockTimeout is used
+const DefaultLockTimeout = 10 * time.Minute
+
+// fileLock is an advisory lock on a file, held until Unlock
+type fileLock struct {
+	f *os.File
+}
+
+// tryLockFile acquires the lock on path without waiting, ok is false if it is held by another process
+func tryLockFile(path string) (l *fileLock, ok bool, err error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+		if err != nil {
+			return nil, false, fmt.Errorf("error opening lock file: %w", err)
+		}
+		ok, err = tryLock(f)
+		if err != nil || !ok {
+			_ = f.Close()
+			return nil, false, err
+		}
+		// the holder before may have removed the lock file, the lock is then taken again on
+		// the file now at path
+		if current, err := f.Stat(); err == nil {
+			if fi, err := os.Stat(path); err == nil && os.SameFile(current, fi) {
+				return &fileLock{f: f}, true, nil
+			}
+		}
+		_ = unlock(f)
+		_ = f.Close()
+	}
+}
+
+// Unlock releases the lock
+func (l *fileLock) Unlock() error {
+	return errors.Join(unlock(l.f), l.f.Close())
+}
+
+// Remove removes the lock file and releases the lock. Processes waiting for it lock a new
+// file instead. Where an open file cannot be removed, it is removed after releasing the lock
+// unless another process opened it meanwhile
+func (l *fileLock) Remove() error {
+	if err := os.Remove(l.f.Name()); err == nil || errors.Is(err, os.ErrNotExist) {
+		return l.Unlock()
+	}
+	if err := l.Unlock(); err != nil {
+		return err
+	}
+	_ = os.Remove(l.f.Name())
+	return nil
+}
+
+// waitForLock acquires the lock on path, waiting up to timeout while it is held by another
+// process. ErrLocked is returned if the lock is still held after timeout
+func waitForLock(ctx context.Context, path string, timeout time.Duration, logger *slog.Logger) (*fileLock, error) {
+	deadline := time.Now().Add(timeout)
+	logged := false
+	for {
+		l, ok, err := tryLockFile(path)
+		if err != nil || ok {
+			return l, err
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w: %s is held by another godl process", ErrLocked, path)
+		}
+		if !logged {
+			logger.Info("waiting for another godl process", "lock", path, "timeout", timeout)
+			logged = true
+		}
+		if err := sleep(ctx, min(lockPollInterval, time.Until(deadline))); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// lockFile acquires the lock on path, waiting up to the lock timeout of a
+func (a *Application) lockFile(ctx context.Context, path string) (*fileLock, error) {
+	return waitForLock(ctx, path, a.lockTimeout, a.logger)
+}
+
+// lockDestination locks the destination directory dir against concurrent changes
+func (a *Application) lockDestination(ctx context.Context, dir string) (*fileLock, error) {
+	return a.lockFile(ctx, filepath.Join(dir, destinationLockName))
+}
+
+// lockVersion locks the installation of version in dir
+func (a *Application) lockVersion(ctx context.Context, dir, version string) (*fileLock, error) {
+	return a.lockFile(ctx, versionLockPath(dir, version))
+}
+
+// lockCacheEntry locks the cached archive at path, so processes sharing the cache never
+// download or promote the same archive concurrently and it is not removed while in use
+func (a *Application) lockCacheEntry(ctx context.Context, path string) (*fileLock, error) {
+	for {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("error creating cache directory: %w", err)
+		}
+		l, err := a.lockFile(ctx, path+lockSuffix)
+		// the directory is removed if the cache is cleaned meanwhile
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return l, err
+	}
+}
+
+// versionLockPath returns the lock file guarding the installation of version in dir
+func versionLockPath(dir, version string) string {
+	return filepath.Join(dir, fmt.Sprintf("_%s.lock", version))
+}
+
+// unlockWithWarning releases l, logging failures
+func (a *Application) unlockWithWarning(l *fileLock) {
+	if err := l.Unlock(); err != nil {
+		a.logger.Warn("error releasing lock", "err", err, "lock", l.f.Name())
+	}
+}
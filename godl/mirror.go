@@ -0,0 +1,93 @@
+package godl
+
+import (
+	"errors"
+	"log/slog"
+	"net/url"
+	"sync"
+)
+
+type (
+	// mirror is a server providing the release index and archives below its base url
+	mirror struct {
+		// base url of mirror, archives are located at base/<file name>
+		base *url.URL
+		// failed is set once the mirror failed with a network error
+		failed bool
+	}
+
+	// mirrorSet is the ordered list of mirrors shared by an application and its downloads.
+	// Mirrors failing with network errors are skipped for the rest of the run
+	mirrorSet struct {
+		mu      sync.Mutex
+		mirrors []*mirror
+		logger  *slog.Logger
+	}
+)
+
+// newMirrorSet returns the mirrors for the base urls in the order of preference
+func newMirrorSet(baseUrls []string) (*mirrorSet, error) {
+	if len(baseUrls) == 0 {
+		return nil, errors.New("no mirror provided")
+	}
+	s := &mirrorSet{logger: slog.Default()}
+	for _, baseUrl := range baseUrls {
+		parsed, err := url.Parse(baseUrl)
+		if err != nil {
+			return nil, err
+		}
+		if parsed.Scheme == "" || parsed.Host == "" {
+			return nil, errors.New("mirror " + baseUrl + " is not an absolute url")
+		}
+		s.mirrors = append(s.mirrors, &mirror{base: parsed})
+	}
+	return s, nil
+}
+
+// healthy returns the mirrors that did not fail in the order of preference. If all mirrors
+// failed, all are returned as a last resort
+func (s *mirrorSet) healthy() []*mirror {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []*mirror
+	for _, m := range s.mirrors {
+		if !m.failed {
+			result = append(result, m)
+		}
+	}
+	if len(result) == 0 {
+		return append(result, s.mirrors...)
+	}
+	return result
+}
+
+// first returns the preferred mirror
+func (s *mirrorSet) first() *mirror {
+	return s.mirrors[0]
+}
+
+// report marks m as failed if err is a network error, so it is skipped from now on
+func (s *mirrorSet) report(m *mirror, err error) {
+	if err == nil || !isTransient(err) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m.failed {
+		return
+	}
+	m.failed = true
+	if len(s.mirrors) > 1 {
+		s.logger.Warn("mirror failed, skipping it for the rest of the run", "mirror", m.base.String(), "err", err)
+	}
+}
+
+// urls returns the urls of fileName on all healthy mirrors along with the mirror
+func (s *mirrorSet) urls(fileName string) ([]*url.URL, []*mirror) {
+	mirrors := s.healthy()
+	urls := make([]*url.URL, len(mirrors))
+	for i, m := range mirrors {
+		urls[i] = m.base.JoinPath(fileName)
+	}
+	return urls, mirrors
+}
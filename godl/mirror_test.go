@@ -0,0 +1,95 @@
+package godl
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMirrorFailover(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tc := range []struct {
+		name   string
+		failed func(t *testing.T) string
+	}{
+		{
+			name: "unreachable",
+			failed: func(t *testing.T) string {
+				srv := httptest.NewServer(http.NotFoundHandler())
+				srv.Close()
+				return srv.URL + "/dl/"
+			},
+		},
+		{
+			name: "feed-unavailable",
+			failed: func(t *testing.T) string {
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Query().Get("mode") != "json" {
+						t.Errorf("unexpected request of download page %s", r.URL)
+					}
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}))
+				t.Cleanup(srv.Close)
+				return srv.URL + "/dl/"
+			},
+		},
+		{
+			name: "archive-unavailable",
+			failed: func(t *testing.T) string {
+				feed := newInstallTestServer(t, nil)
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Query().Get("mode") == "json" {
+						res, err := http.Get(feed.URL + r.URL.String())
+						if err != nil {
+							t.Errorf("unexpected error: %s", err)
+							return
+						}
+						defer func() { _ = res.Body.Close() }()
+						_, _ = io.Copy(w, res.Body)
+						return
+					}
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}))
+				t.Cleanup(srv.Close)
+				return srv.URL + "/dl/"
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			archiveRequests := 0
+			srv := newInstallTestServer(t, &archiveRequests)
+			dir := t.TempDir()
+
+			a, err := NewApplication(context.Background(), WithLogger(logger), WithMirrors(tc.failed(t), srv.URL+"/dl/"), WithCacheDir(t.TempDir()), WithRetries(0))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(a.Downloads) != 1 {
+				t.Fatalf("expected 1 download, got %d", len(a.Downloads))
+			}
+			p, err := a.Install(context.Background(), "1.22.1", dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if _, err := os.Stat(filepath.Join(p, "VERSION")); err != nil {
+				t.Errorf("expected VERSION in installation: %s", err)
+			}
+			if archiveRequests != 1 {
+				t.Errorf("expected 1 archive request to second mirror, got %d", archiveRequests)
+			}
+		})
+	}
+}
+
+func TestNewMirrorSetInvalid(t *testing.T) {
+	for _, baseUrls := range [][]string{nil, {"relative/path"}, {"https://example.com", "://"}} {
+		if _, err := newMirrorSet(baseUrls); err == nil {
+			t.Errorf("expected error for %v", baseUrls)
+		}
+	}
+}
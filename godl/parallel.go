@@ -0,0 +1,259 @@
+package godl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// minParallelSize is the minimum size of archives downloaded using parallel range requests
+var minParallelSize int64 = 16 << 20
+
+type (
+	// byteRange is the part of a parallel download fetched by a single range request
+	byteRange struct {
+		// Start is the offset of the first byte
+		Start int64 `json:"start"`
+		// End is the offset of the last byte
+		End int64 `json:"end"`
+		// Written is the number of bytes from Start already in the file
+		Written int64 `json:"written"`
+	}
+
+	// rangeState is the progress of a parallel download, saved next to its data when the
+	// download fails so a retry only requests the missing bytes
+	rangeState struct {
+		// Size of the archive in bytes
+		Size int64 `json:"size"`
+		// Ranges covering the archive in order
+		Ranges []byteRange `json:"ranges"`
+	}
+)
+
+// sidecarFileName returns the name of a file belonging to the download to partialFileName,
+// it ends in partialSuffix as well so it is kept along with the partial download
+func sidecarFileName(partialFileName, kind string) string {
+	return strings.TrimSuffix(partialFileName, partialSuffix) + "." + kind + partialSuffix
+}
+
+// newRangeState splits size bytes into connections ranges
+func newRangeState(size int64, connections int) *rangeState {
+	state := &rangeState{Size: size}
+	chunkSize := (size + int64(connections) - 1) / int64(connections)
+	for start := int64(0); start < size; start += chunkSize {
+		state.Ranges = append(state.Ranges, byteRange{Start: start, End: min(start+chunkSize, size) - 1})
+	}
+	return state
+}
+
+// loadRangeState returns the saved progress of the parallel download to chunksFileName, nil
+// if there is none or it does not match the data or size
+func loadRangeState(chunksFileName, stateFileName string, size int64) *rangeState {
+	data, err := os.ReadFile(stateFileName)
+	if err != nil {
+		return nil
+	}
+	var state rangeState
+	if err := json.Unmarshal(data, &state); err != nil || state.Size != size {
+		return nil
+	}
+	next := int64(0)
+	for _, r := range state.Ranges {
+		if r.Start != next || r.End < r.Start || r.Written < 0 || r.Written > r.End-r.Start+1 {
+			return nil
+		}
+		next = r.End + 1
+	}
+	if next != size {
+		return nil
+	}
+	if fi, err := os.Stat(chunksFileName); err != nil || fi.Size() != size {
+		return nil
+	}
+	return &state
+}
+
+// written returns the number of bytes already downloaded
+func (s *rangeState) written() int64 {
+	var n int64
+	for _, r := range s.Ranges {
+		n += r.Written
+	}
+	return n
+}
+
+// prefix returns the number of bytes from the start of the archive already downloaded
+func (s *rangeState) prefix() int64 {
+	var n int64
+	for _, r := range s.Ranges {
+		n += r.Written
+		if r.Written < r.End-r.Start+1 {
+			break
+		}
+	}
+	return n
+}
+
+// rangeSize returns the size of u if the server supports range requests for it, 0 otherwise
+func (d *Download) rangeSize(ctx context.Context, u string) (int64, error) {
+	res, err := httpDo(ctx, d.Client, http.MethodHead, u, "")
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && !isTransient(err) {
+		// e.g. HEAD not allowed, download using a single stream
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	_ = res.Body.Close()
+	if res.Header.Get("Accept-Ranges") != "bytes" || res.ContentLength <= 0 {
+		return 0, nil
+	}
+	if d.Size > 0 && res.ContentLength != d.Size {
+		return 0, fmt.Errorf("%w: %s has %d bytes, expected %d", ErrNetwork, u, res.ContentLength, d.Size)
+	}
+	return res.ContentLength, nil
+}
+
+// downloadParallel downloads u to partialFileName using d.Connections concurrent range
+// requests into a pre-allocated file. The progress of the ranges is saved if the download
+// fails, so the next attempt continues them. ok is false without error if the server does
+// not support range requests, the archive is small or a partial download is to be resumed,
+// bytes downloaded in parallel before are then moved to partialFileName as far as they are
+// complete from the start
+func (d *Download) downloadParallel(ctx context.Context, u, partialFileName string) (bool, error) {
+	if fi, err := os.Stat(partialFileName); err == nil && fi.Size() > 0 {
+		return false, nil
+	}
+	size, err := d.rangeSize(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	chunksFileName := sidecarFileName(partialFileName, "chunks")
+	stateFileName := sidecarFileName(partialFileName, "ranges")
+	state := loadRangeState(chunksFileName, stateFileName, size)
+	if size < minParallelSize {
+		return false, promoteRangePrefix(chunksFileName, stateFileName, partialFileName, state)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if state == nil {
+		state = newRangeState(size, d.Connections)
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(chunksFileName, flags, 0600)
+	if err != nil {
+		return false, fmt.Errorf("error creating file: %w", err)
+	}
+	if err := f.Truncate(size); err != nil {
+		_ = f.Close()
+		_ = os.Remove(chunksFileName)
+		return false, fmt.Errorf("error allocating file: %w", err)
+	}
+	if written := state.written(); written > 0 {
+		d.Logger.Info("resuming parallel download", "file", d.FileName, "bytes", written, "size", size)
+	} else {
+		d.Logger.Debug("downloading using parallel range requests", "file", d.FileName, "connections", len(state.Ranges), "size", size)
+	}
+
+	progress := newProgressReporter(d.Progress, PhaseDownload, d.FileName, size)
+	progress.addBytes(state.written())
+	errs := make([]error, len(state.Ranges))
+	var wg sync.WaitGroup
+	for i := range state.Ranges {
+		r := &state.Ranges[i]
+		if r.Written > r.End-r.Start {
+			continue
+		}
+		// the other ranges continue if one fails, so a retry has less to request
+		wg.Go(func() {
+			errs[i] = d.downloadRange(ctx, u, f, r, progress)
+		})
+	}
+	wg.Wait()
+
+	err = firstError(errs)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		data, marshalErr := json.Marshal(state)
+		if marshalErr == nil {
+			marshalErr = writeFileAtomic(stateFileName, data)
+		}
+		if marshalErr != nil {
+			d.Logger.Warn("error saving progress of parallel download", "err", marshalErr, "file", stateFileName)
+		}
+		return true, err
+	}
+	if err := os.Remove(stateFileName); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return true, err
+	}
+	progress.done()
+	return true, os.Rename(chunksFileName, partialFileName)
+}
+
+// promoteRangePrefix moves the bytes of an earlier parallel download complete from the start
+// to partialFileName, so they are resumed using a single stream
+func promoteRangePrefix(chunksFileName, stateFileName, partialFileName string, state *rangeState) error {
+	defer func() {
+		_ = os.Remove(stateFileName)
+		_ = os.Remove(chunksFileName)
+	}()
+	if state == nil || state.prefix() == 0 {
+		return nil
+	}
+	if err := os.Truncate(chunksFileName, state.prefix()); err != nil {
+		return err
+	}
+	return os.Rename(chunksFileName, partialFileName)
+}
+
+// firstError returns the first error of errs that is not caused by cancelling the download
+func firstError(errs []error) error {
+	var result error
+	for _, err := range errs {
+		if err != nil && (result == nil || errors.Is(result, context.Canceled)) {
+			result = err
+		}
+	}
+	return result
+}
+
+// downloadRange writes the missing bytes of r to f, r.Written is updated with the bytes
+// written even if the download fails
+func (d *Download) downloadRange(ctx context.Context, u string, f *os.File, r *byteRange, progress *progressReporter) error {
+	start := r.Start + r.Written
+	res, err := httpDo(ctx, d.Client, http.MethodGet, u, fmt.Sprintf("bytes=%d-%d", start, r.End))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := res.Body.Close()
+		if err != nil {
+			d.Logger.Warn("error closing http body", "err", err)
+		}
+	}()
+	if res.StatusCode != http.StatusPartialContent || !strings.HasPrefix(res.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-%d/", start, r.End)) {
+		return fmt.Errorf("%w: unexpected response to range request for bytes %d-%d: %s", ErrNetwork, start, r.End, res.Status)
+	}
+	var w io.Writer = io.NewOffsetWriter(f, start)
+	if progress != nil {
+		w = progressWriter{w: w, r: progress}
+	}
+	n, err := io.CopyN(w, networkReader{res.Body}, r.End-start+1)
+	r.Written += n
+	if errors.Is(err, io.EOF) {
+		err = fmt.Errorf("%w: response ended after %d of %d bytes", ErrNetwork, n, r.End-start+1)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing bytes to file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package godl
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum time between two progress reports of a phase
+const progressInterval = 100 * time.Millisecond
+
+// ProgressPhase is the step of an installation progress is reported for
+type ProgressPhase string
+
+const (
+	// PhaseDownload is reported while an archive is downloaded
+	PhaseDownload ProgressPhase = "download"
+	// PhaseExtract is reported while an archive is extracted
+	PhaseExtract ProgressPhase = "extract"
+)
+
+type (
+	// Progress is the state of a download or extraction
+	Progress struct {
+		// Phase reported
+		Phase ProgressPhase
+		// FileName of the archive downloaded or extracted
+		FileName string
+		// Bytes of the archive downloaded or extracted so far, including a resumed download
+		Bytes int64
+		// Total size of the archive in bytes, 0 if unknown
+		Total int64
+		// Files extracted so far
+		Files int
+		// Done is set for the last report of a phase
+		Done bool
+	}
+
+	// ProgressFunc is called with the progress of downloads and extractions, see WithProgress
+	ProgressFunc func(Progress)
+
+	// progressReporter throttles the reports of a single phase to progressInterval
+	progressReporter struct {
+		mu       sync.Mutex
+		fn       ProgressFunc
+		progress Progress
+		last     time.Time
+	}
+
+	// progressWriter reports the bytes written to it
+	progressWriter struct {
+		w io.Writer
+		r *progressReporter
+	}
+
+	// progressReader reports the bytes read from it
+	progressReader struct {
+		r  io.Reader
+		pr *progressReporter
+	}
+)
+
+// newProgressReporter returns a reporter calling fn for phase, nil if fn is nil
+func newProgressReporter(fn ProgressFunc, phase ProgressPhase, fileName string, total int64) *progressReporter {
+	if fn == nil {
+		return nil
+	}
+	return &progressReporter{fn: fn, progress: Progress{Phase: phase, FileName: fileName, Total: total}}
+}
+
+// update changes the progress using change and reports it unless reported recently
+func (r *progressReporter) update(change func(p *Progress)) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change(&r.progress)
+	if now := time.Now(); now.Sub(r.last) >= progressInterval {
+		r.last = now
+		r.fn(r.progress)
+	}
+}
+
+// addBytes adds n to the bytes processed
+func (r *progressReporter) addBytes(n int64) {
+	r.update(func(p *Progress) { p.Bytes += n })
+}
+
+// addFile counts an extracted file taking n bytes in the archive
+func (r *progressReporter) addFile(n int64) {
+	r.update(func(p *Progress) {
+		p.Bytes += n
+		p.Files++
+	})
+}
+
+// done reports the final progress of the phase
+func (r *progressReporter) done() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.progress.Done = true
+	r.fn(r.progress)
+}
+
+// Write implements io.Writer
+func (w progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.r.addBytes(int64(n))
+	return n, err
+}
+
+// Read implements io.Reader
+func (r progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.pr.addBytes(int64(n))
+	return n, err
+}
@@ -0,0 +1,162 @@
+package godl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// archiveNameRegex extracts version, os and arch from the file name of a release archive
+var archiveNameRegex = regexp.MustCompile(`^(go[0-9.]+(?:(?:beta|rc)[0-9]+)?)\.([a-z0-9]+)-([a-z0-9]+)\.(?:tar\.gz|zip)$`)
+
+// MirrorHandler serves the archives of a Cache as a release mirror using the layout of
+// go.dev/dl: the JSON release feed at its root and the archives below it, so an application
+// created with WithBaseUrl or WithMirrors pointing at the handler installs from it
+type MirrorHandler struct {
+	cache  *Cache
+	logger *slog.Logger
+}
+
+// NewMirrorHandler returns a handler serving the archives in cache
+func NewMirrorHandler(cache *Cache, logger *slog.Logger) *MirrorHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &MirrorHandler{cache: cache, logger: logger}
+}
+
+// ServeHTTP implements http.Handler. Archives are served supporting range requests, the
+// sha256 of an archive is used as its ETag
+func (h *MirrorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	entries, err := h.cache.List()
+	if err != nil {
+		h.logger.Error("error listing cached archives", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	h.logger.Debug("serving request", "method", r.Method, "path", r.URL.Path, "range", r.Header.Get("Range"))
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if name == "" {
+		h.serveFeed(w, r, entries)
+		return
+	}
+	for _, e := range servedEntries(entries) {
+		if e.FileName == name {
+			h.serveArchive(w, r, e)
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// servedEntries returns the archives of entries with a valid checksum and a release archive
+// name, the most recently cached first
+func servedEntries(entries []CacheEntry) []CacheEntry {
+	var result []CacheEntry
+	for _, e := range entries {
+		if sum, err := hex.DecodeString(e.Sha256); err != nil || len(sum) != sha256.Size {
+			continue
+		}
+		if !archiveNameRegex.MatchString(e.FileName) {
+			continue
+		}
+		result = append(result, e)
+	}
+	slices.SortStableFunc(result, func(a, b CacheEntry) int {
+		return b.ModTime.Compare(a.ModTime)
+	})
+	return result
+}
+
+// feed returns the releases of entries in the format of the go.dev JSON release feed, the
+// newest release first
+func feed(entries []CacheEntry) []release {
+	var releases []release
+	seen := make(map[string]bool)
+	for _, e := range servedEntries(entries) {
+		if seen[e.FileName] {
+			continue
+		}
+		seen[e.FileName] = true
+		m := archiveNameRegex.FindStringSubmatch(e.FileName)
+		v, err := Parse(m[1])
+		if err != nil {
+			continue
+		}
+		i := slices.IndexFunc(releases, func(r release) bool { return r.Version == m[1] })
+		if i < 0 {
+			releases = append(releases, release{Version: m[1], Stable: v.IsStable()})
+			i = len(releases) - 1
+		}
+		releases[i].Files = append(releases[i].Files, releaseFile{
+			FileName: e.FileName,
+			Os:       m[2],
+			Arch:     m[3],
+			Version:  m[1],
+			Sha256:   e.Sha256,
+			Size:     e.Size,
+			Kind:     "archive",
+		})
+	}
+	slices.SortFunc(releases, func(a, b release) int {
+		va, _ := Parse(a.Version)
+		vb, _ := Parse(b.Version)
+		return vb.Compare(va)
+	})
+	for i := range releases {
+		slices.SortFunc(releases[i].Files, func(a, b releaseFile) int {
+			return strings.Compare(a.FileName, b.FileName)
+		})
+	}
+	return releases
+}
+
+// serveFeed writes the release feed of entries, its ETag is the sha256 of the feed
+func (h *MirrorHandler) serveFeed(w http.ResponseWriter, r *http.Request, entries []CacheEntry) {
+	releases := feed(entries)
+	if releases == nil {
+		releases = []release{}
+	}
+	data, err := json.Marshal(releases)
+	if err != nil {
+		h.logger.Error("error encoding release feed", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(data)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:])+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+// serveArchive writes the archive of e
+func (h *MirrorHandler) serveArchive(w http.ResponseWriter, r *http.Request, e CacheEntry) {
+	f, err := os.Open(e.Path)
+	if err != nil {
+		h.logger.Error("error opening cached archive", "err", err, "path", e.Path)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		err := f.Close()
+		if err != nil {
+			h.logger.Warn("error closing cached archive", "err", err, "path", e.Path)
+		}
+	}()
+	w.Header().Set("ETag", `"`+e.Sha256+`"`)
+	http.ServeContent(w, r, e.FileName, e.ModTime, f)
+}
@@ -0,0 +1,128 @@
+package godl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMirrorHandler(t *testing.T) {
+	upstream := newInstallTestServer(t, nil)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cacheDir := t.TempDir()
+	a, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(upstream.URL+"/dl/"), WithCacheDir(cacheDir))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := a.Install(context.Background(), "1.22.1", t.TempDir()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	upstream.Close()
+	d := a.Downloads[0]
+	archive, err := os.ReadFile(filepath.Join(cacheDir, "archives", d.Sha256, d.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// other platforms are listed, partial downloads and unknown files are not
+	other := filepath.Join(cacheDir, "archives", strings.Repeat("ab", 32))
+	for _, name := range []string{"go1.21.0.plan9-386.zip", "go1.23rc1.plan9-386.zip" + partialSuffix, "notes.txt"} {
+		if err := os.MkdirAll(other, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(other, name), []byte("archive"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/dl/", http.StripPrefix("/dl", NewMirrorHandler(NewCache(cacheDir), logger)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/dl/?mode=json&include=all")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var releases []release
+	err = json.NewDecoder(res.Body).Decode(&releases)
+	_ = res.Body.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []release{
+		{Version: "go1.22.1", Stable: true, Files: []releaseFile{{FileName: d.FileName, Os: runtime.GOOS, Arch: runtime.GOARCH, Version: "go1.22.1", Sha256: d.Sha256, Size: d.Size, Kind: "archive"}}},
+		{Version: "go1.21.0", Stable: true, Files: []releaseFile{{FileName: "go1.21.0.plan9-386.zip", Os: "plan9", Arch: "386", Version: "go1.21.0", Sha256: strings.Repeat("ab", 32), Size: 7, Kind: "archive"}}},
+	}
+	if diff := cmp.Diff(expected, releases); diff != "" {
+		t.Errorf("mismatch in expectation: \n\n%s", diff)
+	}
+
+	// another godl installs from the mirror
+	mirrored, err := NewApplication(context.Background(), WithLogger(logger), WithBaseUrl(srv.URL+"/dl/"), WithCacheDir(t.TempDir()), WithConnections(2))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := mirrored.Install(context.Background(), "1.22.1", t.TempDir()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{name: "archive", method: http.MethodGet, path: "/dl/" + d.FileName, status: http.StatusOK, body: string(archive)},
+		{name: "range", method: http.MethodGet, path: "/dl/" + d.FileName, headers: map[string]string{"Range": "bytes=10-19"}, status: http.StatusPartialContent, body: string(archive[10:20])},
+		{name: "etag", method: http.MethodGet, path: "/dl/" + d.FileName, headers: map[string]string{"If-None-Match": `"` + d.Sha256 + `"`}, status: http.StatusNotModified},
+		{name: "etag-changed", method: http.MethodGet, path: "/dl/" + d.FileName, headers: map[string]string{"If-None-Match": `"other"`}, status: http.StatusOK, body: string(archive)},
+		{name: "if-range-changed", method: http.MethodGet, path: "/dl/" + d.FileName, headers: map[string]string{"Range": "bytes=10-19", "If-Range": `"other"`}, status: http.StatusOK, body: string(archive)},
+		{name: "partial", method: http.MethodGet, path: "/dl/go1.23rc1.plan9-386.zip", status: http.StatusNotFound},
+		{name: "not-an-archive", method: http.MethodGet, path: "/dl/notes.txt", status: http.StatusNotFound},
+		{name: "traversal", method: http.MethodGet, path: "/dl/../index.json", status: http.StatusNotFound},
+		{name: "method", method: http.MethodPost, path: "/dl/" + d.FileName, status: http.StatusMethodNotAllowed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, srv.URL+tc.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer func() { _ = res.Body.Close() }()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, res.StatusCode)
+			}
+			if tc.status == http.StatusOK || tc.status == http.StatusPartialContent {
+				if string(body) != tc.body {
+					t.Errorf("unexpected body of %d bytes", len(body))
+				}
+				if etag := res.Header.Get("ETag"); etag != fmt.Sprintf("%q", d.Sha256) {
+					t.Errorf("unexpected ETag %s", etag)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package godl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+)
+
+const (
+	// defaultSumDB is the checksum database used if GOSUMDB is not configured
+	defaultSumDB = "sum.golang.org"
+)
+
+// knownSumDBs maps the names of checksum databases to their verifier keys
+var knownSumDBs = map[string]string{
+	"sum.golang.org": "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ux18htTTAD8OuAn8",
+}
+
+type (
+	// sumDB is a checksum database as configured by a GOSUMDB setting
+	sumDB struct {
+		// key verifying the signed tree heads of the database
+		key string
+		// name of database
+		name string
+		// base is the url of the database if set explicitly, otherwise the database is
+		// accessed through the proxies or directly at https://<name>
+		base *url.URL
+	}
+
+	// sumDBClient provides the operations of the checksum database client, the latest signed
+	// tree head and the fetched tiles are kept in dir if not empty
+	sumDBClient struct {
+		a   *Application
+		ctx context.Context
+		db  *sumDB
+		dir string
+
+		once    sync.Once
+		base    *url.URL
+		baseErr error
+
+		mu     sync.Mutex
+		memory map[string][]byte
+	}
+)
+
+// parseSumDB parses a GOSUMDB setting (name, key or key followed by url), nil is returned for off
+func parseSumDB(gosumdb string) (*sumDB, error) {
+	if gosumdb == "sum.golang.google.cn" {
+		gosumdb = "sum.golang.org https://sum.golang.google.cn"
+	}
+	if gosumdb == "off" {
+		return nil, nil
+	}
+	fields := strings.Fields(gosumdb)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("invalid GOSUMDB %q", gosumdb)
+	}
+	if key, ok := knownSumDBs[fields[0]]; ok {
+		fields[0] = key
+	}
+	verifier, err := note.NewVerifier(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid GOSUMDB: %w", err)
+	}
+	db := &sumDB{key: fields[0], name: verifier.Name()}
+	if len(fields) == 2 {
+		db.base, err = url.Parse(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid GOSUMDB url: %w", err)
+		}
+	}
+	return db, nil
+}
+
+// readSumFile returns the hash of the module version from the go.sum style file name, empty
+// if the file does not list it
+func readSumFile(name, path, version string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == path && fields[1] == version {
+			return fields[2], nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// toolchainSum returns the go.sum hash of the toolchain module version, taken from the sum file
+// or looked up in the checksum database. Toolchains are never installed unverified, without a
+// checksum database (GOSUMDB=off, GONOSUMDB or offline) the sum file has to list the version
+func (a *Application) toolchainSum(ctx context.Context, version string) (string, error) {
+	if a.sumFile != "" {
+		sum, err := readSumFile(a.sumFile, toolchainModule, version)
+		if err != nil {
+			return "", fmt.Errorf("error reading sum file: %w", err)
+		}
+		if sum != "" {
+			a.logger.Debug("using checksum from sum file", "version", version, "file", a.sumFile)
+			return sum, nil
+		}
+	}
+	reason := ""
+	switch {
+	case a.sumDB == nil:
+		reason = "checksum database disabled by GOSUMDB=off"
+	case module.MatchPrefixPatterns(a.noSumDB, toolchainModule):
+		reason = toolchainModule + " matches GONOSUMDB"
+	case a.offline:
+		reason = "offline"
+	}
+	if reason != "" {
+		return "", fmt.Errorf("%w: %s and no checksum for %s@%s in sum file, refusing to download", ErrChecksumMismatch, reason, toolchainModule, version)
+	}
+
+	client := &sumDBClient{a: a, ctx: ctx, db: a.sumDB, base: a.sumDB.base}
+	if a.cache != nil {
+		client.dir = filepath.Join(a.cache.Dir(), "sumdb")
+	}
+	lines, err := sumdb.NewClient(client).Lookup(toolchainModule, version)
+	if err != nil {
+		return "", fmt.Errorf("error looking up %s@%s in checksum database %s: %w", toolchainModule, version, a.sumDB.name, err)
+	}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == toolchainModule && fields[1] == version {
+			return fields[2], nil
+		}
+	}
+	return "", fmt.Errorf("%w: checksum database %s has no hash for %s@%s", ErrChecksumMismatch, a.sumDB.name, toolchainModule, version)
+}
+
+// initBase determines the url of the database. Like the go command a proxy is used if it
+// supports proxying the database, the database is accessed directly otherwise
+func (c *sumDBClient) initBase() {
+	if c.base != nil {
+		return
+	}
+	for _, p := range c.a.goProxies {
+		if p.url == nil || p.url.String() == directToolchainProxy {
+			break
+		}
+		u := p.url.JoinPath("sumdb", c.db.name)
+		_, err := c.a.readUrl(c.ctx, u.JoinPath("supported"))
+		if err == nil {
+			c.base = u
+			return
+		}
+		if !p.fallBackOnError && !isNotFound(err) {
+			c.baseErr = err
+			return
+		}
+	}
+	c.base, c.baseErr = url.Parse("https://" + c.db.name)
+}
+
+// ReadRemote implements sumdb.ClientOps
+func (c *sumDBClient) ReadRemote(path string) ([]byte, error) {
+	c.once.Do(c.initBase)
+	if c.baseErr != nil {
+		return nil, c.baseErr
+	}
+	return c.a.readUrl(c.ctx, c.base.JoinPath(path))
+}
+
+// ReadConfig implements sumdb.ClientOps, a missing latest tree head is returned as empty
+func (c *sumDBClient) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(c.db.key), nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := c.readConfig(file)
+	if data == nil && err == nil {
+		return []byte{}, nil
+	}
+	return data, err
+}
+
+// WriteConfig implements sumdb.ClientOps
+func (c *sumDBClient) WriteConfig(file string, old, new []byte) error {
+	if file == "key" {
+		return errors.New("cannot write key")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current, err := c.readConfig(file)
+	if err != nil {
+		return err
+	}
+	if len(current) > 0 && !bytes.Equal(current, old) {
+		return sumdb.ErrWriteConflict
+	}
+	if c.dir == "" {
+		if c.memory == nil {
+			c.memory = make(map[string][]byte)
+		}
+		c.memory[file] = new
+		return nil
+	}
+	return writeFileAtomic(filepath.Join(c.dir, "config", filepath.FromSlash(file)), new)
+}
+
+// readConfig returns the content of the configuration file, nil if it does not exist. c.mu
+// has to be held
+func (c *sumDBClient) readConfig(file string) ([]byte, error) {
+	if c.dir == "" {
+		return c.memory[file], nil
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, "config", filepath.FromSlash(file)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// ReadCache implements sumdb.ClientOps
+func (c *sumDBClient) ReadCache(file string) ([]byte, error) {
+	if c.dir == "" {
+		return nil, fs.ErrNotExist
+	}
+	return os.ReadFile(filepath.Join(c.dir, "cache", filepath.FromSlash(file)))
+}
+
+// WriteCache implements sumdb.ClientOps
+func (c *sumDBClient) WriteCache(file string, data []byte) {
+	if c.dir == "" {
+		return
+	}
+	if err := writeFileAtomic(filepath.Join(c.dir, "cache", filepath.FromSlash(file)), data); err != nil {
+		c.a.logger.Debug("could not cache checksum database file", "file", file, "err", err)
+	}
+}
+
+// Log implements sumdb.ClientOps
+func (c *sumDBClient) Log(msg string) {
+	c.a.logger.Debug(msg)
+}
+
+// SecurityError implements sumdb.ClientOps, the lookup fails with sumdb.ErrSecurity
+func (c *sumDBClient) SecurityError(msg string) {
+	c.a.logger.Error("checksum database misbehaving", "err", msg)
+}
+
+// writeFileAtomic writes data to a temporary file next to name and renames it to name
+func writeFileAtomic(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
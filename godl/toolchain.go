@@ -0,0 +1,388 @@
+package godl
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+const (
+	// toolchainModule is the module path the go command downloads toolchains as
+	toolchainModule = "golang.org/toolchain"
+	// toolchainVersionPrefix precedes the go version in toolchain module versions, e.g.
+	// v0.0.1-go1.22.1.linux-amd64
+	toolchainVersionPrefix = "v0.0.1-"
+	// directToolchainProxy serves golang.org/toolchain for GOPROXY=direct as announced by
+	// its go-import meta tag
+	directToolchainProxy = "https://go.dev/dl/mod"
+	// moduleKind is the kind of downloads fetched as toolchain module
+	moduleKind = "module"
+)
+
+// goProxy is a single entry of a GOPROXY list
+type goProxy struct {
+	// url of proxy, nil for off
+	url *url.URL
+	// fallBackOnError is set for entries followed by a pipe, any error tries the next entry.
+	// Otherwise only not found responses do
+	fallBackOnError bool
+}
+
+// parseGoProxy parses a GOPROXY list, direct is replaced by the origin of golang.org/toolchain
+func parseGoProxy(goproxy string) ([]goProxy, error) {
+	var proxies []goProxy
+	for goproxy != "" {
+		entry, fallBackOnError := goproxy, false
+		if i := strings.IndexAny(goproxy, ",|"); i >= 0 {
+			entry, fallBackOnError, goproxy = goproxy[:i], goproxy[i] == '|', goproxy[i+1:]
+		} else {
+			goproxy = ""
+		}
+		entry = strings.TrimSpace(entry)
+		switch entry {
+		case "":
+			continue
+		case "off":
+			proxies = append(proxies, goProxy{})
+			continue
+		case "direct":
+			entry = directToolchainProxy
+		}
+		u, err := url.Parse(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GOPROXY entry %q: %w", entry, err)
+		}
+		if u.Scheme != "file" && (u.Scheme == "" || u.Host == "") {
+			return nil, fmt.Errorf("invalid GOPROXY entry %q: not an absolute url", entry)
+		}
+		proxies = append(proxies, goProxy{url: u, fallBackOnError: fallBackOnError})
+	}
+	if len(proxies) == 0 {
+		return nil, errors.New("no GOPROXY provided")
+	}
+	return proxies, nil
+}
+
+// toolchainFileUrl returns the url of name below the toolchain module on p
+func toolchainFileUrl(p goProxy, name string) *url.URL {
+	return p.url.JoinPath(toolchainModule, "@v", name)
+}
+
+// toolchainUrl returns the url of name on the first proxy
+func (a *Application) toolchainUrl(name string) *url.URL {
+	for _, p := range a.goProxies {
+		if p.url != nil {
+			return toolchainFileUrl(p, name)
+		}
+	}
+	direct, _ := url.Parse(directToolchainProxy)
+	return toolchainFileUrl(goProxy{url: direct}, name)
+}
+
+// fileUrlPath returns the local path of a file url
+func fileUrlPath(u *url.URL) string {
+	p := u.Path
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p)
+}
+
+// isNotFound returns true if err tells a proxy does not know the requested file
+func isNotFound(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone
+	}
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// proxyFetch calls fetch with the url of name below the toolchain module on each proxy until
+// one succeeds. Like the go command the next proxy is only tried if the file was not found
+// or the proxy is followed by a pipe. Offline only file urls are used
+func (a *Application) proxyFetch(ctx context.Context, name string, fetch func(u *url.URL) error) error {
+	err := fmt.Errorf("%w: no proxy provides %s@%s", fs.ErrNotExist, toolchainModule, name)
+	for _, p := range a.goProxies {
+		if p.url == nil {
+			return fmt.Errorf("%w: module download disabled by GOPROXY=off", err)
+		}
+		u := toolchainFileUrl(p, name)
+		if a.offline && u.Scheme != "file" {
+			err = fmt.Errorf("%w: offline, skipping %s", ErrNetwork, p.url.Redacted())
+			continue
+		}
+		err = fetch(u)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if !p.fallBackOnError && !isNotFound(err) {
+			return err
+		}
+		a.logger.Debug("proxy failed, trying next one", "url", u.Redacted(), "err", err)
+	}
+	return err
+}
+
+// readUrl returns the content of u, file urls are read from disk
+func (a *Application) readUrl(ctx context.Context, u *url.URL) ([]byte, error) {
+	if u.Scheme == "file" {
+		return os.ReadFile(fileUrlPath(u))
+	}
+	res, err := httpGet(ctx, a.httpClient, u.String(), 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := res.Body.Close()
+		if err != nil {
+			a.logger.Warn("error closing http response body", "err", err)
+		}
+	}()
+	return io.ReadAll(networkReader{res.Body})
+}
+
+// queryToolchains reads all known go versions from the list of toolchain module versions
+func (a *Application) queryToolchains(ctx context.Context) error {
+	var list []byte
+	err := a.proxyFetch(ctx, "list", func(u *url.URL) error {
+		var err error
+		list, err = a.readUrl(ctx, u)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error listing toolchains: %w", err)
+	}
+
+	releases := make(map[string]*release)
+	scanner := bufio.NewScanner(bytes.NewReader(list))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		f, ok := parseToolchainVersion(fields[0])
+		if !ok {
+			a.logger.Debug("skipping toolchain with unknown version format", "version", fields[0])
+			continue
+		}
+		r, ok := releases[f.Version]
+		if !ok {
+			v, _ := Parse(f.Version)
+			r = &release{Version: f.Version, Stable: v.IsStable()}
+			releases[f.Version] = r
+		}
+		r.Files = append(r.Files, f)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading toolchain list: %w", err)
+	}
+
+	index := make([]release, 0, len(releases))
+	for _, r := range releases {
+		index = append(index, *r)
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Version < index[j].Version })
+	for i := range index {
+		a.processToolchainRelease(index[i])
+	}
+	if a.cache != nil {
+		if err := a.cache.saveIndex(toolchainIndexFile, index); err != nil {
+			a.logger.Warn("error saving release index to cache", "err", err)
+		}
+	}
+	return nil
+}
+
+// parseToolchainVersion returns the release file for a toolchain module version like
+// v0.0.1-go1.22.1.linux-amd64
+func parseToolchainVersion(moduleVersion string) (releaseFile, bool) {
+	rest, ok := strings.CutPrefix(moduleVersion, toolchainVersionPrefix)
+	if !ok {
+		return releaseFile{}, false
+	}
+	i := strings.LastIndex(rest, ".")
+	if i < 0 {
+		return releaseFile{}, false
+	}
+	goos, goarch, ok := strings.Cut(rest[i+1:], "-")
+	if !ok || goos == "" || goarch == "" {
+		return releaseFile{}, false
+	}
+	if _, err := Parse(rest[:i]); err != nil {
+		return releaseFile{}, false
+	}
+	return releaseFile{
+		FileName: moduleVersion + ".zip",
+		Os:       goos,
+		Arch:     releaseArch(goarch),
+		Version:  rest[:i],
+		Kind:     moduleKind,
+	}, true
+}
+
+// processToolchainRelease is transforming the toolchain modules of a release to our internal
+// version representation, it will skip over modules that are not for the selected OS or arch
+func (a *Application) processToolchainRelease(r release) {
+	for _, f := range r.Files {
+		if f.Kind != moduleKind || f.Os != a.goos || f.Arch != a.goarch {
+			continue
+		}
+		v, err := Parse(f.Version)
+		if err != nil {
+			continue
+		}
+		if !v.IsStable() && (!a.includeReleaseCandidates || !strings.HasPrefix(v.Pre, "rc")) {
+			continue
+		}
+		a.Downloads = append(a.Downloads, Download{
+			Url:         a.toolchainUrl(f.FileName),
+			Version:     v,
+			GoOs:        f.Os,
+			GoArch:      f.Arch,
+			FileName:    f.FileName,
+			Kind:        f.Kind,
+			Logger:      a.logger,
+			Client:      a.httpClient,
+			Retries:     a.retries,
+			Progress:    a.progress,
+			Connections: a.connections,
+		})
+	}
+}
+
+// toolchainArchive downloads the toolchain module zip of d to downloadDestination using the
+// proxies and verifies it against the sum file or the checksum database
+func (a *Application) toolchainArchive(ctx context.Context, d *Download, downloadDestination string) (string, error) {
+	version := strings.TrimSuffix(d.FileName, ".zip")
+	sum, err := a.toolchainSum(ctx, version)
+	if err != nil {
+		return "", err
+	}
+
+	fileName := filepath.Join(downloadDestination, d.FileName)
+	partialFileName := fileName + partialSuffix
+	err = a.proxyFetch(ctx, d.FileName, func(u *url.URL) error {
+		if u.Scheme == "file" {
+			return copyFile(fileUrlPath(u), partialFileName)
+		}
+		proxied := *d
+		proxied.Url = u
+		return proxied.downloadWithRetries(ctx, partialFileName)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error downloading: %w", err)
+	}
+
+	actual, err := dirhash.HashZip(partialFileName, dirhash.Hash1)
+	if err == nil && actual != sum {
+		err = &ChecksumMismatchError{FileName: d.FileName, Expected: sum, Actual: actual}
+	} else if err != nil {
+		err = fmt.Errorf("%w: %w", ErrArchiveCorrupt, err)
+	}
+	if err != nil {
+		if removeErr := os.Remove(partialFileName); removeErr != nil {
+			a.logger.Warn("error removing failed download", "err", removeErr, "file", partialFileName)
+		}
+		return "", err
+	}
+	a.logger.Debug("verified toolchain module", "version", version, "hash", sum)
+	return fileName, os.Rename(partialFileName, fileName)
+}
+
+// copyFile copies src to dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// toolchainExecutable returns true for the files of a toolchain that have to be executable,
+// module zips do not record file modes
+func toolchainExecutable(name string) bool {
+	if strings.HasPrefix(name, "bin/") || strings.HasPrefix(name, "pkg/tool/") {
+		return true
+	}
+	matched, _ := path.Match("go_?*_?*_exec", path.Base(name))
+	return strings.HasPrefix(name, "lib/") && matched
+}
+
+// extractToolchain unpacks the toolchain module zipFile of d to dst/go, restoring the file
+// modes and the go.mod files renamed for the module zip. Extraction stops when ctx is done
+func (a *Application) extractToolchain(ctx context.Context, d *Download, zipFile, dst string) error {
+	archive, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrArchiveCorrupt, err)
+	}
+	defer func() {
+		err := archive.Close()
+		if err != nil {
+			a.logger.Warn("error closing zip archive", "err", err)
+		}
+	}()
+
+	prefix := toolchainModule + "@" + strings.TrimSuffix(d.FileName, ".zip") + "/"
+	root := filepath.Join(filepath.Clean(dst), "go")
+	progress := a.zipProgress(archive, zipFile)
+	for _, f := range archive.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok || f.FileInfo().IsDir() {
+			return fmt.Errorf("%w: unexpected entry %q in toolchain module", ErrArchiveCorrupt, f.Name)
+		}
+		filePath, err := withinRoot(root, name)
+		if err != nil {
+			return err
+		}
+		a.logger.Debug("extracting file", "path", filePath)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return err
+		}
+		if err := a.extractZipFile(f, filePath); err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if toolchainExecutable(name) {
+			mode = 0755
+		}
+		if err := os.Chmod(filePath, mode); err != nil {
+			return err
+		}
+		// go.mod files are stored as _go.mod, as a module zip must not contain other modules
+		if path.Base(name) == "_go.mod" {
+			if err := copyFile(filePath, filepath.Join(filepath.Dir(filePath), "go.mod")); err != nil {
+				return err
+			}
+		}
+		progress.addFile(int64(f.CompressedSize64))
+	}
+	progress.done()
+	return nil
+}
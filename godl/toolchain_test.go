@@ -0,0 +1,239 @@
+package godl
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/dirhash"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// testToolchainVersion is the module version of the toolchain served by newToolchainTestServer
+var testToolchainVersion = fmt.Sprintf("v0.0.1-go1.22.1.%s-%s", runtime.GOOS, runtime.GOARCH)
+
+// buildToolchainZip returns a toolchain module zip for version and its go.sum hash
+func buildToolchainZip(t *testing.T, version string) ([]byte, string) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"VERSION":     "go1.22.1",
+		"bin/go":      "#!/bin/sh\n",
+		"src/_go.mod": "module std\n",
+	} {
+		f, err := w.Create(toolchainModule + "@" + version + "/" + name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p := filepath.Join(t.TempDir(), "toolchain.zip")
+	if err := os.WriteFile(p, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sum, err := dirhash.HashZip(p, dirhash.Hash1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return buf.Bytes(), sum
+}
+
+// newToolchainTestServer serves the toolchain module for the running platform and a checksum
+// database with the verifier key returned, publishing sum as the hash of the module. Zip
+// downloads are counted in zipRequests if not nil
+func newToolchainTestServer(t *testing.T, archive []byte, sum string, zipRequests *int) (*httptest.Server, string) {
+	skey, vkey, err := note.GenerateKey(rand.Reader, "sum.example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	db := sumdb.NewServer(sumdb.NewTestServer(skey, func(path, vers string) ([]byte, error) {
+		if path != toolchainModule || vers != testToolchainVersion {
+			return nil, fmt.Errorf("unknown module %s@%s", path, vers)
+		}
+		return []byte(fmt.Sprintf("%s %s %s\n%s %s/go.mod h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=\n", path, vers, sum, path, vers)), nil
+	}))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/golang.org/toolchain/@v/list", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "v0.0.1-go1.22.1.plan9-mips\n%s\nv0.0.1-go1.23rc1.%s-%s\n", testToolchainVersion, runtime.GOOS, runtime.GOARCH)
+	})
+	mux.HandleFunc("/golang.org/toolchain/@v/"+testToolchainVersion+".zip", func(w http.ResponseWriter, r *http.Request) {
+		if zipRequests != nil {
+			*zipRequests++
+		}
+		_, _ = w.Write(archive)
+	})
+	mux.HandleFunc("/sumdb/sum.example.com/supported", func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/sumdb/sum.example.com/", http.StripPrefix("/sumdb/sum.example.com", db))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, vkey
+}
+
+func TestInstallToolchain(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	archive, sum := buildToolchainZip(t, testToolchainVersion)
+	fileProxy := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(fileProxy, "golang.org", "toolchain", "@v"), 0755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for name, content := range map[string][]byte{"list": []byte(testToolchainVersion + "\n"), testToolchainVersion + ".zip": archive} {
+		if err := os.WriteFile(filepath.Join(fileProxy, "golang.org", "toolchain", "@v", name), content, 0644); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	sumFile := filepath.Join(t.TempDir(), "go.sum")
+	if err := os.WriteFile(sumFile, []byte(fmt.Sprintf("%s %s %s\n", toolchainModule, testToolchainVersion, sum)), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(notFound.Close)
+
+	for _, tc := range []struct {
+		name        string
+		publish     string
+		opts        func(proxy, vkey string) []ApplicationOption
+		err         error
+		zipRequests int
+	}{
+		{
+			name:    "sumdb",
+			publish: sum,
+			opts: func(proxy, vkey string) []ApplicationOption {
+				return []ApplicationOption{WithGoProxy(notFound.URL + "," + proxy), WithGoSumDB(vkey, "")}
+			},
+			zipRequests: 1,
+		},
+		{
+			name:    "sumdb-mismatch",
+			publish: "h1:" + strings.Repeat("A", 43) + "=",
+			opts: func(proxy, vkey string) []ApplicationOption {
+				return []ApplicationOption{WithGoProxy(proxy), WithGoSumDB(vkey, "")}
+			},
+			err:         ErrChecksumMismatch,
+			zipRequests: 1,
+		},
+		{
+			name:    "sumdb-off",
+			publish: sum,
+			opts: func(proxy, vkey string) []ApplicationOption {
+				return []ApplicationOption{WithGoProxy(proxy), WithGoSumDB("off", "")}
+			},
+			err: ErrChecksumMismatch,
+		},
+		{
+			name:    "nosumdb-sum-file",
+			publish: "h1:" + strings.Repeat("A", 43) + "=",
+			opts: func(proxy, vkey string) []ApplicationOption {
+				return []ApplicationOption{WithGoProxy(proxy), WithGoSumDB(vkey, "golang.org"), WithSumFile(sumFile)}
+			},
+			zipRequests: 1,
+		},
+		{
+			name:    "file-proxy",
+			publish: sum,
+			opts: func(proxy, vkey string) []ApplicationOption {
+				u := url.URL{Scheme: "file", Path: filepath.ToSlash(fileProxy)}
+				return []ApplicationOption{WithGoProxy(u.String()), WithGoSumDB("off", ""), WithSumFile(sumFile)}
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			zipRequests := 0
+			srv, vkey := newToolchainTestServer(t, archive, tc.publish, &zipRequests)
+			dir := t.TempDir()
+
+			a, err := NewApplication(context.Background(), append(tc.opts(srv.URL, vkey), WithLogger(logger), WithCacheDir(t.TempDir()))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(a.Downloads) != 1 || a.Downloads[0].Kind != moduleKind {
+				t.Fatalf("expected one toolchain module, got %v", a.Downloads)
+			}
+			p, err := a.Install(context.Background(), "1.22", dir)
+			if zipRequests != tc.zipRequests {
+				t.Errorf("expected %d zip requests, got %d", tc.zipRequests, zipRequests)
+			}
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected %v, got %v", tc.err, err)
+				}
+				if _, err := os.Stat(filepath.Join(dir, "1.22.1")); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("expected nothing to be installed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if p != filepath.Join(dir, "1.22.1") {
+				t.Errorf("unexpected installation path %s", p)
+			}
+			if data, err := os.ReadFile(filepath.Join(p, "src", "go.mod")); err != nil || string(data) != "module std\n" {
+				t.Errorf("expected src/go.mod restored, got %q, %v", data, err)
+			}
+			fi, err := os.Stat(filepath.Join(p, "bin", "go"))
+			if err != nil {
+				t.Fatalf("expected bin/go in installation: %s", err)
+			}
+			if runtime.GOOS != "windows" && fi.Mode().Perm()&0111 == 0 {
+				t.Errorf("expected bin/go to be executable, got %s", fi.Mode())
+			}
+		})
+	}
+}
+
+func TestParseGoProxy(t *testing.T) {
+	for _, tc := range []struct {
+		goproxy  string
+		expected []string
+		err      bool
+	}{
+		{goproxy: "https://proxy.golang.org,direct", expected: []string{"https://proxy.golang.org ,", directToolchainProxy + " ,"}},
+		{goproxy: "https://athens.example.com|https://proxy.golang.org,off", expected: []string{"https://athens.example.com |", "https://proxy.golang.org ,", "off ,"}},
+		{goproxy: "file:///srv/proxy", expected: []string{"file:///srv/proxy ,"}},
+		{goproxy: "", err: true},
+		{goproxy: "proxy.golang.org", err: true},
+	} {
+		t.Run(tc.goproxy, func(t *testing.T) {
+			proxies, err := parseGoProxy(tc.goproxy)
+			if tc.err != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var actual []string
+			for _, p := range proxies {
+				entry, separator := "off", ","
+				if p.url != nil {
+					entry = p.url.String()
+				}
+				if p.fallBackOnError {
+					separator = "|"
+				}
+				actual = append(actual, entry+" "+separator)
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("mismatch in expectation: \n\n%s", diff)
+			}
+		})
+	}
+}
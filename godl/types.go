@@ -0,0 +1,131 @@
+package godl
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+type (
+	// Download is a single Go download
+	Download struct {
+		// Url is pointing to the archive
+		Url *url.URL
+		// Version of download
+		Version Version
+		// GoOs of download
+		GoOs string
+		// GoArch of download
+		GoArch string
+		// FileName of download
+		FileName string
+		// Kind of download (archive, installer, source or module for toolchain modules)
+		Kind string
+		// Size of download in bytes
+		Size int64
+		// Sha256 is the published checksum of the download
+		Sha256 string
+		// Logger is used for logging
+		Logger *slog.Logger
+		// Client is used for http requests
+		Client *http.Client
+		// Retries is the number of times a download failing with a transient error is retried
+		Retries int
+		// Connections is the number of concurrent range requests used for downloading, the
+		// archive is downloaded using a single request if not greater than 1
+		Connections int
+		// Progress is called while downloading if not nil
+		Progress ProgressFunc
+		// mirrors the download is available from in addition to Url, may be nil
+		mirrors *mirrorSet
+	}
+
+	// Application is the base for all business logic
+	Application struct {
+		// mirrors serving the release index and archives
+		mirrors *mirrorSet
+		// Downloads found
+		Downloads []Download
+		// versionRegex is a regular expression that extracts the single values
+		versionRegex *regexp.Regexp
+		// verbose is used to control verbosity
+		verbose bool
+		// includeReleaseCandidates will show release candidates as something to install
+		includeReleaseCandidates bool
+		// forceDownload replaces an existing installation
+		forceDownload bool
+		// logger is used for logging
+		logger *slog.Logger
+		// httpClient is used for all http requests
+		httpClient *http.Client
+		// retries is the number of times a failed download is retried
+		retries int
+		// cache stores downloaded archives, downloads are not cached if nil
+		cache *Cache
+		// offline uses the cached release index and archives only
+		offline bool
+		// localOnly skips querying the releases on construction
+		localOnly bool
+		// goos is the operating system downloads are selected for
+		goos string
+		// goarch is the architecture downloads are selected for
+		goarch string
+		// forceLink replaces a file or directory at the place of the link
+		forceLink bool
+		// lockTimeout is the time to wait for a lock held by another process
+		lockTimeout time.Duration
+		// goProxies fetch toolchains as golang.org/toolchain modules instead of go.dev if not nil
+		goProxies []goProxy
+		// sumDB verifies toolchain modules, nil if disabled
+		sumDB *sumDB
+		// noSumDB lists the module path patterns not verified using sumDB
+		noSumDB string
+		// sumFile is a go.sum style file listing toolchain module hashes
+		sumFile string
+		// progress is called with the progress of downloads and extractions if not nil
+		progress ProgressFunc
+		// connections is the number of concurrent range requests per download, 0 (default) and 1
+		// download using a single request
+		connections int
+	}
+
+	// release is a single entry of the go.dev JSON release feed
+	release struct {
+		// Version of release, including the go prefix
+		Version string `json:"version"`
+		// Stable is true for stable releases
+		Stable bool `json:"stable"`
+		// Files belonging to release
+		Files []releaseFile `json:"files"`
+	}
+
+	// releaseFile is a single file of a release in the go.dev JSON release feed
+	releaseFile struct {
+		// FileName of file
+		FileName string `json:"filename"`
+		// Os of file, empty for source
+		Os string `json:"os"`
+		// Arch of file, empty for source
+		Arch string `json:"arch"`
+		// Version of file, including the go prefix
+		Version string `json:"version"`
+		// Sha256 checksum of file
+		Sha256 string `json:"sha256"`
+		// Size of file in bytes
+		Size int64 `json:"size"`
+		// Kind of file (archive, installer or source)
+		Kind string `json:"kind"`
+	}
+
+	// ApplicationOption can be used to control behavior
+	ApplicationOption func(application *Application) error
+)
+
+const (
+	BaseUrl = "https://go.dev/dl/"
+
+	// defaultRetries is the number of retries for failed downloads if not configured
+	defaultRetries = 3
+)
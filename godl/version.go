@@ -0,0 +1,116 @@
+package godl
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// PreBeta marks a beta release
+	PreBeta = "beta"
+	// PreRc marks a release candidate
+	PreRc = "rc"
+)
+
+// Version is a go release version such as 1.22.3, 1.21.0, 1.20, 1.23rc1 or 1.22beta1
+type Version struct {
+	// Major version
+	Major int
+	// Minor version
+	Minor int
+	// Patch version, 0 for the first release of a minor version
+	Patch int
+	// Pre is PreBeta or PreRc for pre-releases and empty for final releases
+	Pre string
+	// PreNum is the number of the beta or release candidate
+	PreNum int
+}
+
+var versionFormatRegex = regexp.MustCompile(`^(?:go)?([1-9][0-9]{0,3})\.([0-9]{1,4})(?:\.([0-9]{1,4}))?(?:(beta|rc)([1-9][0-9]{0,3}))?$`)
+
+// Parse parses a go version, the toolchain form with a go prefix (go1.22.3) is accepted
+func Parse(s string) (Version, error) {
+	m := versionFormatRegex.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("invalid go version %q", s)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	v.Pre = m[4]
+	if m[5] != "" {
+		v.PreNum, _ = strconv.Atoi(m[5])
+	}
+	return v, nil
+}
+
+// preRank orders pre-releases before final releases
+func (v Version) preRank() int {
+	switch v.Pre {
+	case PreBeta:
+		return 0
+	case PreRc:
+		return 1
+	}
+	return 2
+}
+
+// Compare returns -1, 0 or 1 if v is older, equal or newer than o
+func (v Version) Compare(o Version) int {
+	for _, d := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}, {v.preRank(), o.preRank()}, {v.PreNum, o.PreNum}} {
+		if d[0] < d[1] {
+			return -1
+		}
+		if d[0] > d[1] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// IsZero returns true for the zero value
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// IsStable returns true for final releases
+func (v Version) IsStable() bool {
+	return v.Pre == ""
+}
+
+// String returns the version as used by go releases: the first release of a minor version
+// is 1.21.0 since go 1.21 and 1.20 before, pre-releases never carry a patch version of 0
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d", v.Major, v.Minor)
+	if v.Patch > 0 || (v.Pre == "" && (v.Major > 1 || v.Minor >= 21)) {
+		s += fmt.Sprintf(".%d", v.Patch)
+	}
+	if v.Pre != "" {
+		s += fmt.Sprintf("%s%d", v.Pre, v.PreNum)
+	}
+	return s
+}
+
+// Toolchain returns the version in the form used by the go toolchain, e.g. go1.22.3
+func (v Version) Toolchain() string {
+	return "go" + v.String()
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (v *Version) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
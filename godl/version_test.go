@@ -0,0 +1,107 @@
+package godl
+
+import (
+	"testing"
+)
+
+// mustParse returns the parsed version or panics
+func mustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+var testCasesParse = []struct {
+	input    string
+	expected Version
+	str      string
+	invalid  bool
+}{
+	{input: "1.22.3", expected: Version{Major: 1, Minor: 22, Patch: 3}, str: "1.22.3"},
+	{input: "go1.22.3", expected: Version{Major: 1, Minor: 22, Patch: 3}, str: "1.22.3"},
+	{input: "1.21.0", expected: Version{Major: 1, Minor: 21}, str: "1.21.0"},
+	{input: "1.20", expected: Version{Major: 1, Minor: 20}, str: "1.20"},
+	{input: "1.20.0", expected: Version{Major: 1, Minor: 20}, str: "1.20"},
+	{input: "1.21rc2", expected: Version{Major: 1, Minor: 21, Pre: PreRc, PreNum: 2}, str: "1.21rc2"},
+	{input: "1.22beta1", expected: Version{Major: 1, Minor: 22, Pre: PreBeta, PreNum: 1}, str: "1.22beta1"},
+	{input: "go1.23rc1", expected: Version{Major: 1, Minor: 23, Pre: PreRc, PreNum: 1}, str: "1.23rc1"},
+	{input: "12.0", expected: Version{Major: 12}, str: "12.0.0"},
+	{input: "", invalid: true},
+	{input: "1", invalid: true},
+	{input: "1.22.x", invalid: true},
+	{input: "0.1", invalid: true},
+	{input: "1.22rc", invalid: true},
+	{input: "1.22alpha1", invalid: true},
+	{input: "v1.22.3", invalid: true},
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range testCasesParse {
+		t.Run(tc.input, func(t *testing.T) {
+			v, err := Parse(tc.input)
+			if tc.invalid {
+				if err == nil {
+					t.Fatalf("expected error, got %v", v)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if v != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, v)
+			}
+			if v.String() != tc.str {
+				t.Errorf("expected %s, got %s", tc.str, v.String())
+			}
+		})
+	}
+}
+
+var testCasesCompare = []struct {
+	a, b     string
+	expected int
+}{
+	{a: "1.21.0", b: "1.21rc2", expected: 1},
+	{a: "1.21rc2", b: "1.21rc10", expected: -1},
+	{a: "1.21beta1", b: "1.21rc1", expected: -1},
+	{a: "1.20", b: "1.20.0", expected: 0},
+	{a: "1.9", b: "1.10", expected: -1},
+	{a: "2.0.0", b: "1.99.99", expected: 1},
+	{a: "1.22.10", b: "1.22.9", expected: 1},
+}
+
+func TestCompare(t *testing.T) {
+	for _, tc := range testCasesCompare {
+		t.Run(tc.a+"-"+tc.b, func(t *testing.T) {
+			a, b := mustParse(tc.a), mustParse(tc.b)
+			if r := a.Compare(b); r != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, r)
+			}
+			if r := b.Compare(a); r != -tc.expected {
+				t.Errorf("expected %d for reversed comparison, got %d", -tc.expected, r)
+			}
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, tc := range testCasesParse {
+		f.Add(tc.input)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		v, err := Parse(s)
+		if err != nil {
+			return
+		}
+		again, err := Parse(v.String())
+		if err != nil {
+			t.Fatalf("cannot parse formatted version %q of %q: %s", v.String(), s, err)
+		}
+		if again.Compare(v) != 0 || again != v {
+			t.Fatalf("round trip of %q changed %+v to %+v", s, v, again)
+		}
+	})
+}
@@ -1,26 +1,27 @@
 package main
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
-	"io/fs"
 	"log"
 	"log/slog"
 	"os"
-	"path"
+	"path/filepath"
 	"runtime/debug"
 	"strings"
 
 	"github.com/sascha-andres/reuse/flag"
 
-	"github.com/sascha-andres/godl/internal"
+	"github.com/sascha-andres/godl/godl"
 )
 
 var (
-	printVersions, download, link, forceDownload    bool
-	skipDownload, verbose, includeReleaseCandidates bool
-	toolVersion                                     bool
-	version, destinationDirectory, linkName         string
+	printVersions, download, link, forceDownload bool
+	skipDownload, toolVersion, forceLink         bool
+	version, destinationDirectory, linkName      string
+	output                                       string
+	cfg                                          settings
 )
 
 func init() {
@@ -28,19 +29,45 @@ func init() {
 
 	flag.BoolVar(&toolVersion, "tool-version", false, "print the version of this tool and exit")
 	flag.BoolVar(&printVersions, "print", false, "use to print all versions for current os & arch")
-	flag.BoolVar(&verbose, "verbose", false, "more verbose output")
+	flag.StringVar(&output, "output", "", "output format of -print: table, tsv, json or yaml, one url per line if empty")
+	flag.BoolVar(&cfg.verbose, "verbose", false, "more verbose output")
 	flag.BoolVar(&download, "download", false, "download provided version")
 	flag.BoolVar(&forceDownload, "force-download", false, "force new download")
 	flag.BoolVar(&skipDownload, "skip-download", false, "skip download if it exists")
 	flag.BoolVar(&link, "link", false, "link go version as linkname")
 	flag.StringVar(&linkName, "link-name", "current", "name (path) of symlink")
-	flag.StringVar(&version, "version", "", "download this version")
+	flag.BoolVar(&forceLink, "force-link", false, "replace a file or directory at the place of the symlink")
+	flag.StringVar(&version, "version", "", "download this version or constraint (latest, stable, oldstable, 1.22, >=1.21 <1.23), read from .go-version, go.work or go.mod if empty")
 	flag.StringVar(&destinationDirectory, "destination", "", "save version in this directory")
-	flag.BoolVar(&includeReleaseCandidates, "include-release-candidates", false, "specify to include release candidates")
+	flag.BoolVar(&cfg.includeReleaseCandidates, "include-release-candidates", false, "specify to include release candidates")
+	flag.StringVar(&cfg.caFile, "ca-file", "", "PEM file with additional certificate authorities to trust")
+	flag.StringVar(&cfg.proxy, "proxy", "", "proxy url to use instead of the proxy environment variables")
+	flag.StringVar(&cfg.timeout, "timeout", "", "abort after this duration (e.g. 10m), no timeout if empty")
+	flag.BoolVar(&cfg.offline, "offline", false, "use the cached release index and archives instead of the network")
+	flag.StringVar(&cfg.cacheDir, "cache-dir", "", "directory downloaded archives are cached in, defaults to the user cache directory")
+	flag.StringVar(&cfg.lockTimeout, "lock-timeout", "", "wait this long (e.g. 30s) for other godl processes using the destination, 0 fails immediately, 10m if empty")
+	flag.StringVar(&cfg.goos, "os", "", "operating system to download for (e.g. linux, windows, darwin), defaults to the running one")
+	flag.StringVar(&cfg.goarch, "arch", "", "architecture to download for as GOARCH (e.g. amd64, arm64, arm or armv6l for 32-bit arm), defaults to the running one")
+	flag.StringVar(&cfg.mirror, "mirror", "", "comma separated base urls of mirrors tried in order, defaults to https://go.dev/dl/")
+	flag.StringVar(&cfg.goproxy, "goproxy", "", "fetch toolchains as golang.org/toolchain modules from this GOPROXY list (e.g. https://proxy.golang.org,direct)")
+	flag.StringVar(&cfg.gosumdb, "gosumdb", os.Getenv("GOSUMDB"), "checksum database verifying toolchain modules, defaults to GOSUMDB or sum.golang.org")
+	flag.StringVar(&cfg.gonosumdb, "gonosumdb", cmp.Or(os.Getenv("GONOSUMDB"), os.Getenv("GOPRIVATE")), "module patterns not verified by the checksum database, defaults to GONOSUMDB or GOPRIVATE")
+	flag.StringVar(&cfg.progress, "progress", progressAuto, "progress of downloads and extraction: auto (bar on a terminal, log lines otherwise), bar, log or off")
+	flag.StringVar(&cfg.connections, "connections", defaultConnections, "concurrent range requests used to download an archive, 1 disables parallel downloads")
+	flag.StringVar(&cfg.sumFile, "sum-file", "", "go.sum style file with toolchain module checksums, used before the checksum database")
 }
 
 func main() {
 	log.SetFlags(log.LstdFlags | log.LUTC | log.Lshortfile)
+
+	if handled, err := dispatch(os.Args[1:]); handled {
+		if err != nil {
+			log.Print(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	flag.Parse()
 
 	if toolVersion {
@@ -48,81 +75,92 @@ func main() {
 		fmt.Printf("godl %s build with %s\n", v, goMinorVersion)
 		os.Exit(0)
 	}
-
-	var handlerOpts *slog.HandlerOptions
-	if verbose {
-		handlerOpts = &slog.HandlerOptions{Level: slog.LevelDebug}
-	} else {
-		handlerOpts = &slog.HandlerOptions{Level: slog.LevelInfo}
-	}
-
-	v := "unknown version"
-	if i, ok := debug.ReadBuildInfo(); ok && i.Main.Version != "" {
-		v = i.Main.Version
+	if err := cfg.validate(); err != nil {
+		log.Print(err)
+		os.Exit(1)
 	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stderr, handlerOpts)).With("project", "godl").With("version", v)
+	logger := cfg.newLogger()
 	slog.SetDefault(logger)
 
 	logger.Debug("Starting importer")
 	defer logger.Debug("Finished importer")
 
-	var opts []internal.ApplicationOption
-	if includeReleaseCandidates {
-		opts = append(opts, internal.WithIncludeReleaseCandidates())
-	}
-	opts = append(opts, internal.WithLogger(logger))
-	if verbose {
-		opts = append(opts, internal.WithVerbose())
+	ctx, cancel, err := cfg.context()
+	if err != nil {
+		logger.Error("error parsing timeout", "err", err)
+		os.Exit(1)
 	}
+	defer cancel()
 
-	a, err := internal.NewApplication(opts...)
+	var opts []godl.ApplicationOption
+	if forceDownload {
+		opts = append(opts, godl.WithForceDownload())
+	}
+	if forceLink {
+		opts = append(opts, godl.WithForceLink())
+	}
+	a, err := cfg.newApplication(ctx, logger, opts...)
 	if err != nil {
 		logger.Error("error constructing application", "err", err)
 		os.Exit(1)
 	}
 
 	if printVersions {
-		for i := range a.Downloads {
-			fmt.Println(a.Downloads[i].Url.String())
+		if output == "" {
+			for i := range a.Downloads {
+				fmt.Println(a.Downloads[i].Url.String())
+			}
+			return
+		}
+		releases, err := remoteReleases(a.Downloads, destinationDirectory)
+		if err == nil {
+			err = printRemoteReleases(os.Stdout, output, releases)
+		}
+		if err != nil {
+			logger.Error("error printing versions", "err", err)
+			os.Exit(1)
 		}
 		return
 	}
 
-	if download {
-		if version == "" {
-			log.Print("no version provided")
+	if version == "" && (download || link) {
+		var p string
+		version, p, err = godl.DiscoverVersion(".")
+		if err != nil {
+			logger.Error("no version provided and none found in .go-version, go.work or go.mod", "err", err)
 			os.Exit(1)
 		}
+		logger.Info("using version from file", "version", version, "file", p)
+	}
+
+	if download {
 		if destinationDirectory == "" {
 			log.Print("no destination provided")
 			os.Exit(1)
 		}
 
-		var downloadDestination, saveDestination string
-
-		downloadDestination, saveDestination, canSkip, err := getDestinationDirectories(logger)
-		if err != nil {
-			logger.Error("error getting destination directories", "err", err)
-			os.Exit(1)
+		var p string
+		p, err = a.Install(ctx, version, destinationDirectory)
+		if p != "" {
+			// link the resolved version, not the constraint
+			version = filepath.Base(p)
 		}
-
-		if !canSkip {
-			logger.Debug("Starting download", "destination", downloadDestination)
-			err = downloadGoVersion(a, downloadDestination, saveDestination)
-			if err != nil {
-				logger.Error("error downloading go", "err", err)
-				os.Exit(1)
-			}
-			logger.Debug("download done")
-		} else {
+		switch {
+		case errors.Is(err, godl.ErrAlreadyInstalled) && skipDownload:
 			logger.Debug("version exists, download skipped")
+		case errors.Is(err, godl.ErrAlreadyInstalled):
+			logger.Error("version already exists, not downloading. To set symbolic link, call without -download", "err", err)
+			os.Exit(1)
+		case err != nil:
+			logger.Error("error downloading go", "err", err)
+			os.Exit(1)
 		}
 	}
 
 	if link {
 		logger.Debug("Creating symlink")
-		err = createSymLink()
+		err = a.Use(version, destinationDirectory, linkName)
 		if err != nil {
 			logger.Error("error creating symlink", "err", err)
 			os.Exit(1)
@@ -172,116 +210,3 @@ func toolVersionInfo() (version string, goVersion string) {
 	}
 	return
 }
-
-// createSymLink creates a symlink for go version
-func createSymLink() error {
-	if "" == version {
-		return errors.New("no version provided")
-	}
-	if destinationDirectory == "" {
-		return errors.New("no destination provided")
-	}
-
-	saveDestination := path.Join(destinationDirectory, fmt.Sprintf("%s", version))
-	if _, err := os.Stat(saveDestination); errors.Is(err, fs.ErrNotExist) {
-		return fmt.Errorf("no go version %s in %s", version, destinationDirectory)
-	}
-
-	return internal.Link(saveDestination, internal.CreateSymlinkPath(destinationDirectory, linkName))
-}
-
-// downloadGoVersion will download selected go version
-func downloadGoVersion(a *internal.Application, downloadDestination string, saveDestination string) error {
-	var err error
-	var downloadFile *os.File
-	var goDownload *internal.Download
-
-	err = os.MkdirAll(downloadDestination, 0700)
-	if err != nil {
-		return fmt.Errorf("error creating directory: %s", err)
-	}
-	goDownload, err = a.GetDownload(version)
-	if err != nil {
-		return fmt.Errorf("error selecting download: %s", err)
-	}
-	downloadFileName := path.Join(downloadDestination, goDownload.FileName)
-	downloadFile, err = os.Create(downloadFileName)
-	if err = goDownload.DownloadGoArchive(downloadFile); err != nil {
-		return fmt.Errorf("error downloading: %s", err)
-	}
-	err = downloadFile.Close()
-	if err != nil {
-		return fmt.Errorf("error closing downloaded file: %s", err)
-	}
-	if strings.HasSuffix(downloadFileName, ".tar.gz") {
-		f, err := os.Open(downloadFileName)
-		if err != nil {
-			return fmt.Errorf("error opening downloaded archive: %s", err)
-		}
-		defer func() {
-			err := f.Close()
-			if err != nil {
-				log.Printf("error closing tar archive: %s", err)
-			}
-		}()
-		err = a.Untar(downloadDestination, f)
-		if err != nil {
-			return fmt.Errorf("error extracting downloaded archive: %s", err)
-		}
-	}
-
-	if strings.HasSuffix(downloadFileName, ".zip") {
-		err := a.Unzip(downloadFileName, downloadDestination)
-		if err != nil {
-			return fmt.Errorf("error extracting downloaded archive: %s", err)
-		}
-	}
-
-	goDirectory := path.Join(downloadDestination, "go")
-
-	if _, err := os.Stat(goDirectory); errors.Is(err, fs.ErrNotExist) {
-		return fmt.Errorf("%s expected but not found", goDirectory)
-	}
-
-	err = os.Rename(goDirectory, saveDestination)
-	if err != nil {
-		return fmt.Errorf("could not move do directory: %s", err)
-	}
-
-	err = os.RemoveAll(downloadDestination)
-	if err != nil {
-		return fmt.Errorf("could not remove download destination: %s", err)
-	}
-	return nil
-}
-
-// getDestinationDirectories calculates destination directories: download dir and save dir, save to link and optionally an error
-func getDestinationDirectories(logger *slog.Logger) (string, string, bool, error) {
-	var downloadDestination, saveDestination string
-	if i, err := os.Stat(destinationDirectory); errors.Is(err, fs.ErrNotExist) {
-		return "", "", false, fmt.Errorf("%s does not exist", destinationDirectory)
-	} else {
-		if !i.IsDir() {
-			return "", "", false, fmt.Errorf("%s is not a directory", destinationDirectory)
-		}
-		downloadDestination = path.Join(destinationDirectory, fmt.Sprintf("_%s", version))
-		saveDestination = path.Join(destinationDirectory, fmt.Sprintf("%s", version))
-	}
-	if _, err := os.Stat(downloadDestination); !errors.Is(err, fs.ErrNotExist) {
-		return "", "", false, fmt.Errorf("%s already exists, manual cleanup may be required", downloadDestination)
-	}
-	if _, err := os.Stat(saveDestination); !errors.Is(err, fs.ErrNotExist) {
-		if !forceDownload {
-			if skipDownload {
-				logger.Debug("skipping download, already downloaded")
-				return "", "", true, nil
-			}
-			return "", "", false, fmt.Errorf("%s already exists, not downloading. To set symbolic link, call without -download", saveDestination)
-		}
-		err = os.RemoveAll(saveDestination)
-		if err != nil {
-			return "", "", false, fmt.Errorf("%s already existed and could not be removed", downloadDestination)
-		}
-	}
-	return downloadDestination, saveDestination, false, nil
-}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sascha-andres/godl/godl"
+)
+
+// output formats supported by -output
+const (
+	outputTable = "table"
+	outputTSV   = "tsv"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+type (
+	// remoteRelease is a downloadable archive as printed by ls-remote
+	remoteRelease struct {
+		Version   string `json:"version" yaml:"version"`
+		Os        string `json:"os" yaml:"os"`
+		Arch      string `json:"arch" yaml:"arch"`
+		Kind      string `json:"kind" yaml:"kind"`
+		FileName  string `json:"filename" yaml:"filename"`
+		Url       string `json:"url" yaml:"url"`
+		Size      int64  `json:"size" yaml:"size"`
+		Sha256    string `json:"sha256" yaml:"sha256"`
+		Stable    bool   `json:"stable" yaml:"stable"`
+		Installed bool   `json:"installed" yaml:"installed"`
+	}
+
+	// installedRelease is an installed version as printed by ls
+	installedRelease struct {
+		Version     string    `json:"version" yaml:"version"`
+		Path        string    `json:"path" yaml:"path"`
+		Size        int64     `json:"size" yaml:"size"`
+		InstalledAt time.Time `json:"installed_at" yaml:"installed_at"`
+		Current     bool      `json:"current" yaml:"current"`
+	}
+
+	// cachedArchive is an archive in the cache as printed by cache list
+	cachedArchive struct {
+		FileName string    `json:"filename" yaml:"filename"`
+		Path     string    `json:"path" yaml:"path"`
+		Size     int64     `json:"size" yaml:"size"`
+		Added    time.Time `json:"added" yaml:"added"`
+		Sha256   string    `json:"sha256" yaml:"sha256"`
+	}
+)
+
+// outputFlag registers -output on fs and returns a pointer to its value
+func outputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", envDefault("GODL_OUTPUT", outputTable), "output format: table, tsv, json or yaml")
+}
+
+// checkOutput returns an error if format is not supported
+func checkOutput(format string) error {
+	switch format {
+	case outputTable, outputTSV, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %q, expected table, tsv, json or yaml", format)
+}
+
+// writeOutput writes items in format. Tables and tsv have the columns of header and one line per
+// item as returned by row, human is set for tables to format values for reading
+func writeOutput[T any](w io.Writer, format string, items []T, header []string, row func(item T, human bool) []string) error {
+	if items == nil {
+		items = []T{}
+	}
+	switch format {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case outputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(items); err != nil {
+			return err
+		}
+		return enc.Close()
+	case outputTSV:
+		if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if _, err := fmt.Fprintln(w, strings.Join(row(item, false), "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, item := range items {
+			_, _ = fmt.Fprintln(tw, strings.Join(row(item, true), "\t"))
+		}
+		return tw.Flush()
+	}
+	return checkOutput(format)
+}
+
+// size returns size for a table or tsv
+func size(size int64, human bool) string {
+	if human {
+		return formatBytes(size)
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// timestamp returns t for a table or tsv
+func timestamp(t time.Time, human bool) string {
+	if human {
+		return t.Format("2006-01-02 15:04")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// remoteReleases converts downloads, installed state is taken from the versions installed in
+// destination if not empty
+func remoteReleases(downloads []godl.Download, destination string) ([]remoteRelease, error) {
+	installed := make(map[godl.Version]bool)
+	if destination != "" {
+		installations, err := godl.ListInstalled(destination, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range installations {
+			installed[i.Version] = true
+		}
+	}
+	result := make([]remoteRelease, 0, len(downloads))
+	for _, d := range downloads {
+		result = append(result, remoteRelease{
+			Version:   d.Version.String(),
+			Os:        d.GoOs,
+			Arch:      d.GoArch,
+			Kind:      d.Kind,
+			FileName:  d.FileName,
+			Url:       d.Url.String(),
+			Size:      d.Size,
+			Sha256:    d.Sha256,
+			Stable:    d.Version.IsStable(),
+			Installed: installed[d.Version],
+		})
+	}
+	return result, nil
+}
+
+// printRemoteReleases writes releases in format
+func printRemoteReleases(w io.Writer, format string, releases []remoteRelease) error {
+	return writeOutput(w, format, releases,
+		[]string{"VERSION", "OS", "ARCH", "KIND", "SIZE", "STABLE", "INSTALLED", "SHA256", "URL"},
+		func(r remoteRelease, human bool) []string {
+			return []string{r.Version, r.Os, r.Arch, r.Kind, size(r.Size, human), strconv.FormatBool(r.Stable), strconv.FormatBool(r.Installed), r.Sha256, r.Url}
+		})
+}
+
+// printInstallations writes the installations in format, in tables the linked version is marked with *
+func printInstallations(w io.Writer, format string, installations []godl.Installation) error {
+	releases := make([]installedRelease, 0, len(installations))
+	for _, i := range installations {
+		releases = append(releases, installedRelease{
+			Version:     i.Version.String(),
+			Path:        i.Path,
+			Size:        i.Size,
+			InstalledAt: i.InstalledAt,
+			Current:     i.Current,
+		})
+	}
+	return writeOutput(w, format, releases,
+		[]string{"CURRENT", "VERSION", "SIZE", "INSTALLED", "PATH"},
+		func(r installedRelease, human bool) []string {
+			current := strconv.FormatBool(r.Current)
+			if human {
+				current = ""
+				if r.Current {
+					current = "*"
+				}
+			}
+			return []string{current, r.Version, size(r.Size, human), timestamp(r.InstalledAt, human), r.Path}
+		})
+}
+
+// printCacheEntries writes the cache entries in format
+func printCacheEntries(w io.Writer, format string, entries []godl.CacheEntry) error {
+	archives := make([]cachedArchive, 0, len(entries))
+	for _, e := range entries {
+		archives = append(archives, cachedArchive{
+			FileName: e.FileName,
+			Path:     e.Path,
+			Size:     e.Size,
+			Added:    e.ModTime,
+			Sha256:   e.Sha256,
+		})
+	}
+	return writeOutput(w, format, archives,
+		[]string{"FILE", "SIZE", "ADDED", "SHA256"},
+		func(a cachedArchive, human bool) []string {
+			return []string{a.FileName, size(a.Size, human), timestamp(a.Added, human), a.Sha256}
+		})
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sascha-andres/godl/godl"
+)
+
+// testOutputTime is the installation and cache time used in the output tests
+var testOutputTime = time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+
+// printTestRemoteReleases prints two releases as ls-remote
+func printTestRemoteReleases(w io.Writer, format string) error {
+	return printRemoteReleases(w, format, []remoteRelease{
+		{Version: "1.22.1", Os: "linux", Arch: "amd64", Kind: "archive", FileName: "go1.22.1.linux-amd64.tar.gz", Url: "https://go.dev/dl/go1.22.1.linux-amd64.tar.gz", Size: 68958945, Sha256: "aab", Stable: true, Installed: true},
+		{Version: "1.23rc1", Os: "linux", Arch: "amd64", Kind: "archive", FileName: "go1.23rc1.linux-amd64.tar.gz", Url: "https://go.dev/dl/go1.23rc1.linux-amd64.tar.gz", Size: 512, Sha256: "ccd"},
+	})
+}
+
+// printTestInstallations prints two installations as ls, the first one is linked
+func printTestInstallations(w io.Writer, format string) error {
+	current, err := godl.Parse("1.22.1")
+	if err != nil {
+		return err
+	}
+	previous, err := godl.Parse("1.21.5")
+	if err != nil {
+		return err
+	}
+	return printInstallations(w, format, []godl.Installation{
+		{Version: current, Path: "/opt/go/1.22.1", Size: 230686720, InstalledAt: testOutputTime, Current: true},
+		{Version: previous, Path: "/opt/go/1.21.5", Size: 2048, InstalledAt: testOutputTime.Add(-48 * time.Hour)},
+	})
+}
+
+// printTestCacheEntries prints a cached archive as cache list
+func printTestCacheEntries(w io.Writer, format string) error {
+	return printCacheEntries(w, format, []godl.CacheEntry{
+		{Sha256: "aab", FileName: "go1.22.1.linux-amd64.tar.gz", Path: "/cache/archives/aab/go1.22.1.linux-amd64.tar.gz", Size: 68958945, ModTime: testOutputTime},
+	})
+}
+
+// printTestEmpty prints an empty cache as cache list
+func printTestEmpty(w io.Writer, format string) error {
+	return printCacheEntries(w, format, nil)
+}
+
+var testCasesOutput = []struct {
+	name     string
+	format   string
+	print    func(w io.Writer, format string) error
+	expected string
+}{
+	{
+		name:   "ls-remote-table",
+		format: outputTable,
+		print:  printTestRemoteReleases,
+		expected: `VERSION  OS     ARCH   KIND     SIZE      STABLE  INSTALLED  SHA256  URL
+1.22.1   linux  amd64  archive  65.8 MiB  true    true       aab     https://go.dev/dl/go1.22.1.linux-amd64.tar.gz
+1.23rc1  linux  amd64  archive  512 B     false   false      ccd     https://go.dev/dl/go1.23rc1.linux-amd64.tar.gz
+`,
+	},
+	{
+		name:   "ls-remote-tsv",
+		format: outputTSV,
+		print:  printTestRemoteReleases,
+		expected: "VERSION\tOS\tARCH\tKIND\tSIZE\tSTABLE\tINSTALLED\tSHA256\tURL\n" +
+			"1.22.1\tlinux\tamd64\tarchive\t68958945\ttrue\ttrue\taab\thttps://go.dev/dl/go1.22.1.linux-amd64.tar.gz\n" +
+			"1.23rc1\tlinux\tamd64\tarchive\t512\tfalse\tfalse\tccd\thttps://go.dev/dl/go1.23rc1.linux-amd64.tar.gz\n",
+	},
+	{
+		name:   "ls-remote-json",
+		format: outputJSON,
+		print:  printTestRemoteReleases,
+		expected: `[
+  {
+    "version": "1.22.1",
+    "os": "linux",
+    "arch": "amd64",
+    "kind": "archive",
+    "filename": "go1.22.1.linux-amd64.tar.gz",
+    "url": "https://go.dev/dl/go1.22.1.linux-amd64.tar.gz",
+    "size": 68958945,
+    "sha256": "aab",
+    "stable": true,
+    "installed": true
+  },
+  {
+    "version": "1.23rc1",
+    "os": "linux",
+    "arch": "amd64",
+    "kind": "archive",
+    "filename": "go1.23rc1.linux-amd64.tar.gz",
+    "url": "https://go.dev/dl/go1.23rc1.linux-amd64.tar.gz",
+    "size": 512,
+    "sha256": "ccd",
+    "stable": false,
+    "installed": false
+  }
+]
+`,
+	},
+	{
+		name:   "ls-remote-yaml",
+		format: outputYAML,
+		print:  printTestRemoteReleases,
+		expected: `- version: 1.22.1
+  os: linux
+  arch: amd64
+  kind: archive
+  filename: go1.22.1.linux-amd64.tar.gz
+  url: https://go.dev/dl/go1.22.1.linux-amd64.tar.gz
+  size: 68958945
+  sha256: aab
+  stable: true
+  installed: true
+- version: 1.23rc1
+  os: linux
+  arch: amd64
+  kind: archive
+  filename: go1.23rc1.linux-amd64.tar.gz
+  url: https://go.dev/dl/go1.23rc1.linux-amd64.tar.gz
+  size: 512
+  sha256: ccd
+  stable: false
+  installed: false
+`,
+	},
+	{
+		name:   "ls-table",
+		format: outputTable,
+		print:  printTestInstallations,
+		expected: `CURRENT  VERSION  SIZE       INSTALLED         PATH
+*        1.22.1   220.0 MiB  2024-03-05 14:30  /opt/go/1.22.1
+         1.21.5   2.0 KiB    2024-03-03 14:30  /opt/go/1.21.5
+`,
+	},
+	{
+		name:   "ls-tsv",
+		format: outputTSV,
+		print:  printTestInstallations,
+		expected: "CURRENT\tVERSION\tSIZE\tINSTALLED\tPATH\n" +
+			"true\t1.22.1\t230686720\t2024-03-05T14:30:00Z\t/opt/go/1.22.1\n" +
+			"false\t1.21.5\t2048\t2024-03-03T14:30:00Z\t/opt/go/1.21.5\n",
+	},
+	{
+		name:   "ls-json",
+		format: outputJSON,
+		print:  printTestInstallations,
+		expected: `[
+  {
+    "version": "1.22.1",
+    "path": "/opt/go/1.22.1",
+    "size": 230686720,
+    "installed_at": "2024-03-05T14:30:00Z",
+    "current": true
+  },
+  {
+    "version": "1.21.5",
+    "path": "/opt/go/1.21.5",
+    "size": 2048,
+    "installed_at": "2024-03-03T14:30:00Z",
+    "current": false
+  }
+]
+`,
+	},
+	{
+		name:   "ls-yaml",
+		format: outputYAML,
+		print:  printTestInstallations,
+		expected: `- version: 1.22.1
+  path: /opt/go/1.22.1
+  size: 230686720
+  installed_at: 2024-03-05T14:30:00Z
+  current: true
+- version: 1.21.5
+  path: /opt/go/1.21.5
+  size: 2048
+  installed_at: 2024-03-03T14:30:00Z
+  current: false
+`,
+	},
+	{
+		name:   "cache-list-table",
+		format: outputTable,
+		print:  printTestCacheEntries,
+		expected: `FILE                         SIZE      ADDED             SHA256
+go1.22.1.linux-amd64.tar.gz  65.8 MiB  2024-03-05 14:30  aab
+`,
+	},
+	{
+		name:   "cache-list-tsv",
+		format: outputTSV,
+		print:  printTestCacheEntries,
+		expected: "FILE\tSIZE\tADDED\tSHA256\n" +
+			"go1.22.1.linux-amd64.tar.gz\t68958945\t2024-03-05T14:30:00Z\taab\n",
+	},
+	{
+		name:   "cache-list-json",
+		format: outputJSON,
+		print:  printTestCacheEntries,
+		expected: `[
+  {
+    "filename": "go1.22.1.linux-amd64.tar.gz",
+    "path": "/cache/archives/aab/go1.22.1.linux-amd64.tar.gz",
+    "size": 68958945,
+    "added": "2024-03-05T14:30:00Z",
+    "sha256": "aab"
+  }
+]
+`,
+	},
+	{
+		name:   "cache-list-yaml",
+		format: outputYAML,
+		print:  printTestCacheEntries,
+		expected: `- filename: go1.22.1.linux-amd64.tar.gz
+  path: /cache/archives/aab/go1.22.1.linux-amd64.tar.gz
+  size: 68958945
+  added: 2024-03-05T14:30:00Z
+  sha256: aab
+`,
+	},
+	{name: "empty-table", format: outputTable, print: printTestEmpty, expected: "FILE  SIZE  ADDED  SHA256\n"},
+	{name: "empty-tsv", format: outputTSV, print: printTestEmpty, expected: "FILE\tSIZE\tADDED\tSHA256\n"},
+	{name: "empty-json", format: outputJSON, print: printTestEmpty, expected: "[]\n"},
+	{name: "empty-yaml", format: outputYAML, print: printTestEmpty, expected: "[]\n"},
+}
+
+func TestOutput(t *testing.T) {
+	for _, tc := range testCasesOutput {
+		t.Run(tc.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tc.print(&b, tc.format); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(tc.expected, b.String()); diff != "" {
+				t.Errorf("mismatch in expectation: \n\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOutputUnsupported(t *testing.T) {
+	var b bytes.Buffer
+	if err := printTestCacheEntries(&b, "xml"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sascha-andres/godl/godl"
+)
+
+// progress modes of -progress
+const (
+	progressAuto = "auto"
+	progressBar  = "bar"
+	progressLog  = "log"
+	progressOff  = "off"
+)
+
+// progressLogInterval is the interval progress is logged at in log mode
+const progressLogInterval = 5 * time.Second
+
+// progressRenderer renders the progress reported by the application as a single updated line
+// on a terminal or as log lines
+type progressRenderer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	logger *slog.Logger
+	bar    bool
+
+	phase      godl.ProgressPhase
+	fileName   string
+	started    time.Time
+	startBytes int64
+	logged     time.Time
+}
+
+// isTerminal returns true if f is a character device
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// checkProgress returns an error if mode is not supported by -progress
+func checkProgress(mode string) error {
+	switch mode {
+	case progressAuto, progressBar, progressLog, progressOff, "":
+		return nil
+	}
+	return fmt.Errorf("unsupported progress %q, expected auto, bar, log or off", mode)
+}
+
+// progressOptions returns the application options for -progress
+func (s *settings) progressOptions(logger *slog.Logger) ([]godl.ApplicationOption, error) {
+	if err := checkProgress(s.progress); err != nil {
+		return nil, err
+	}
+	r := &progressRenderer{w: os.Stderr, logger: logger}
+	switch s.progress {
+	case progressOff:
+		return nil, nil
+	case progressAuto, "":
+		r.bar = isTerminal(os.Stderr)
+	case progressBar:
+		r.bar = true
+	}
+	return []godl.ApplicationOption{godl.WithProgress(r.report)}, nil
+}
+
+// report renders p
+func (r *progressRenderer) report(p godl.Progress) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	if p.Phase != r.phase || p.FileName != r.fileName {
+		r.phase, r.fileName = p.Phase, p.FileName
+		r.started, r.startBytes, r.logged = now, p.Bytes, now
+	}
+
+	if r.bar {
+		_, _ = fmt.Fprintf(r.w, "\r\033[K%s", r.line(p, now))
+		if p.Done {
+			_, _ = fmt.Fprintln(r.w)
+		}
+		return
+	}
+	if !p.Done && now.Sub(r.logged) < progressLogInterval {
+		return
+	}
+	r.logged = now
+	attrs := []any{"phase", p.Phase, "file", p.FileName, "bytes", p.Bytes, "total", p.Total}
+	if p.Phase == godl.PhaseExtract {
+		attrs = append(attrs, "files", p.Files)
+	}
+	if rate, eta, ok := r.estimate(p, now); ok {
+		attrs = append(attrs, "rate", formatBytes(int64(rate))+"/s", "eta", eta.String())
+	}
+	if p.Done {
+		r.logger.Info("progress done", append(attrs, "elapsed", now.Sub(r.started).Round(100*time.Millisecond).String())...)
+		return
+	}
+	r.logger.Info("progress", attrs...)
+}
+
+// line describes p: phase, file, bytes, rate and estimated time left
+func (r *progressRenderer) line(p godl.Progress, now time.Time) string {
+	parts := []string{string(p.Phase), p.FileName}
+	if p.Phase == godl.PhaseExtract {
+		parts = append(parts, fmt.Sprintf("%d files", p.Files))
+	}
+	if p.Total > 0 {
+		parts = append(parts, fmt.Sprintf("%s / %s (%d%%)", formatBytes(p.Bytes), formatBytes(p.Total), p.Bytes*100/p.Total))
+	} else {
+		parts = append(parts, formatBytes(p.Bytes))
+	}
+	if p.Done {
+		return strings.Join(append(parts, "done in "+now.Sub(r.started).Round(100*time.Millisecond).String()), "  ")
+	}
+	if rate, eta, ok := r.estimate(p, now); ok {
+		parts = append(parts, formatBytes(int64(rate))+"/s")
+		if eta > 0 {
+			parts = append(parts, "ETA "+eta.String())
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// estimate returns the rate in bytes per second since the phase started and the estimated
+// time left, ok is false until there is enough data
+func (r *progressRenderer) estimate(p godl.Progress, now time.Time) (rate float64, eta time.Duration, ok bool) {
+	elapsed := now.Sub(r.started)
+	if p.Done || elapsed < time.Second || p.Bytes <= r.startBytes {
+		return 0, 0, false
+	}
+	rate = float64(p.Bytes-r.startBytes) / elapsed.Seconds()
+	if p.Total > p.Bytes {
+		eta = time.Duration(float64(p.Total-p.Bytes) / rate * float64(time.Second)).Round(time.Second)
+	}
+	return rate, eta, true
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sascha-andres/godl/godl"
+)
+
+// settings are shared by the classic flags and the subcommands
+type settings struct {
+	verbose, includeReleaseCandidates, offline bool
+	caFile, proxy, timeout, cacheDir           string
+	goos, goarch, lockTimeout, mirror          string
+	goproxy, gosumdb, gonosumdb, sumFile       string
+	progress, connections                      string
+}
+
+// defaultConnections is the default of -connections
+const defaultConnections = "4"
+
+// envBool returns the GODL_ environment variable for name parsed as bool, false if not set
+func envBool(name string) bool {
+	b, _ := strconv.ParseBool(os.Getenv(name))
+	return b
+}
+
+// register adds the shared flags to fs, defaults are taken from the GODL_ environment variables
+func (s *settings) register(fs *flag.FlagSet) {
+	fs.BoolVar(&s.verbose, "verbose", envBool("GODL_VERBOSE"), "more verbose output")
+	fs.BoolVar(&s.includeReleaseCandidates, "include-release-candidates", envBool("GODL_INCLUDE_RELEASE_CANDIDATES"), "specify to include release candidates")
+	fs.BoolVar(&s.offline, "offline", envBool("GODL_OFFLINE"), "use the cached release index and archives instead of the network")
+	fs.StringVar(&s.caFile, "ca-file", envDefault("GODL_CA_FILE", ""), "PEM file with additional certificate authorities to trust")
+	fs.StringVar(&s.proxy, "proxy", envDefault("GODL_PROXY", ""), "proxy url to use instead of the proxy environment variables")
+	fs.StringVar(&s.timeout, "timeout", envDefault("GODL_TIMEOUT", ""), "abort after this duration (e.g. 10m), no timeout if empty")
+	fs.StringVar(&s.cacheDir, "cache-dir", envDefault("GODL_CACHE_DIR", ""), "directory downloaded archives are cached in, defaults to the user cache directory")
+	fs.StringVar(&s.goos, "os", envDefault("GODL_OS", ""), "operating system to download for (e.g. linux, windows, darwin), defaults to the running one")
+	fs.StringVar(&s.goarch, "arch", envDefault("GODL_ARCH", ""), "architecture to download for as GOARCH (e.g. amd64, arm64, arm or armv6l for 32-bit arm), defaults to the running one")
+	fs.StringVar(&s.mirror, "mirror", envDefault("GODL_MIRROR", ""), "comma separated base urls of mirrors tried in order, defaults to https://go.dev/dl/")
+	fs.StringVar(&s.goproxy, "goproxy", envDefault("GODL_GOPROXY", ""), "fetch toolchains as golang.org/toolchain modules from this GOPROXY list (e.g. https://proxy.golang.org,direct)")
+	fs.StringVar(&s.gosumdb, "gosumdb", envDefault("GODL_GOSUMDB", os.Getenv("GOSUMDB")), "checksum database verifying toolchain modules, defaults to GOSUMDB or sum.golang.org")
+	fs.StringVar(&s.gonosumdb, "gonosumdb", envDefault("GODL_GONOSUMDB", cmp.Or(os.Getenv("GONOSUMDB"), os.Getenv("GOPRIVATE"))), "module patterns not verified by the checksum database, defaults to GONOSUMDB or GOPRIVATE")
+	fs.StringVar(&s.sumFile, "sum-file", envDefault("GODL_SUM_FILE", ""), "go.sum style file with toolchain module checksums, used before the checksum database")
+	fs.StringVar(&s.progress, "progress", envDefault("GODL_PROGRESS", progressAuto), "progress of downloads and extraction: auto (bar on a terminal, log lines otherwise), bar, log or off")
+	fs.StringVar(&s.connections, "connections", envDefault("GODL_CONNECTIONS", defaultConnections), "concurrent range requests used to download an archive, 1 disables parallel downloads")
+	s.registerLockTimeout(fs)
+}
+
+// registerLockTimeout adds -lock-timeout to fs
+func (s *settings) registerLockTimeout(fs *flag.FlagSet) {
+	fs.StringVar(&s.lockTimeout, "lock-timeout", envDefault("GODL_LOCK_TIMEOUT", ""), "wait this long (e.g. 30s) for other godl processes using the destination, 0 fails immediately, 10m if empty")
+}
+
+// connectionsOptions returns the application options for -connections
+func (s *settings) connectionsOptions() ([]godl.ApplicationOption, error) {
+	if s.connections == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(s.connections)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing connections: %w", err)
+	}
+	if n < 1 {
+		return nil, fmt.Errorf("connections must be at least 1, got %d", n)
+	}
+	return []godl.ApplicationOption{godl.WithConnections(n)}, nil
+}
+
+// lockTimeoutOptions returns the application options for -lock-timeout
+func (s *settings) lockTimeoutOptions() ([]godl.ApplicationOption, error) {
+	if s.lockTimeout == "" {
+		return nil, nil
+	}
+	d, err := s.lockTimeoutDuration()
+	if err != nil {
+		return nil, err
+	}
+	return []godl.ApplicationOption{godl.WithLockTimeout(d)}, nil
+}
+
+// lockTimeoutDuration returns the duration of -lock-timeout, godl.DefaultLockTimeout if empty
+func (s *settings) lockTimeoutDuration() (time.Duration, error) {
+	if s.lockTimeout == "" {
+		return godl.DefaultLockTimeout, nil
+	}
+	d, err := time.ParseDuration(s.lockTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing lock timeout: %w", err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("lock timeout must not be negative, got %s", s.lockTimeout)
+	}
+	return d, nil
+}
+
+// validate returns an error if a flag or its GODL_ default has an invalid value, so it is
+// reported right after parsing
+func (s *settings) validate() error {
+	if s.timeout != "" {
+		if _, err := time.ParseDuration(s.timeout); err != nil {
+			return fmt.Errorf("error parsing timeout: %w", err)
+		}
+	}
+	if _, err := s.lockTimeoutDuration(); err != nil {
+		return err
+	}
+	if _, err := s.connectionsOptions(); err != nil {
+		return err
+	}
+	return checkProgress(s.progress)
+}
+
+// newLogger returns the logger writing to stderr, debug messages are included if verbose
+func (s *settings) newLogger() *slog.Logger {
+	var handlerOpts *slog.HandlerOptions
+	if s.verbose {
+		handlerOpts = &slog.HandlerOptions{Level: slog.LevelDebug}
+	} else {
+		handlerOpts = &slog.HandlerOptions{Level: slog.LevelInfo}
+	}
+
+	v := "unknown version"
+	if i, ok := debug.ReadBuildInfo(); ok && i.Main.Version != "" {
+		v = i.Main.Version
+	}
+
+	return slog.New(slog.NewJSONHandler(os.Stderr, handlerOpts)).With("project", "godl").With("version", v)
+}
+
+// context returns a context cancelled on interrupt, SIGTERM or after the timeout
+func (s *settings) context() (context.Context, context.CancelFunc, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if s.timeout == "" {
+		return ctx, stop, nil
+	}
+	d, err := time.ParseDuration(s.timeout)
+	if err != nil {
+		stop()
+		return nil, nil, fmt.Errorf("error parsing timeout: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return ctx, func() {
+		cancel()
+		stop()
+	}, nil
+}
+
+// newHTTPClient returns the http client configured using -ca-file and -proxy
+func (s *settings) newHTTPClient() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if s.proxy != "" {
+		u, err := url.Parse(s.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing proxy url: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if s.caFile != "" {
+		pem, err := os.ReadFile(s.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading certificate authorities: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", s.caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// newApplication returns the application configured by the settings, opts are applied last
+func (s *settings) newApplication(ctx context.Context, logger *slog.Logger, opts ...godl.ApplicationOption) (*godl.Application, error) {
+	client, err := s.newHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("error constructing http client: %w", err)
+	}
+
+	var appOpts []godl.ApplicationOption
+	appOpts = append(appOpts, godl.WithHTTPClient(client))
+	if s.includeReleaseCandidates {
+		appOpts = append(appOpts, godl.WithIncludeReleaseCandidates())
+	}
+	if s.cacheDir != "" {
+		appOpts = append(appOpts, godl.WithCacheDir(s.cacheDir))
+	}
+	if s.offline {
+		appOpts = append(appOpts, godl.WithOffline())
+	}
+	if s.mirror != "" {
+		appOpts = append(appOpts, godl.WithMirrors(strings.Split(s.mirror, ",")...))
+	}
+	if s.goproxy != "" {
+		appOpts = append(appOpts, godl.WithGoProxy(s.goproxy), godl.WithGoSumDB(s.gosumdb, s.gonosumdb))
+	}
+	if s.sumFile != "" {
+		appOpts = append(appOpts, godl.WithSumFile(s.sumFile))
+	}
+	if s.goos != "" || s.goarch != "" {
+		appOpts = append(appOpts, godl.WithPlatform(cmp.Or(s.goos, runtime.GOOS), cmp.Or(s.goarch, runtime.GOARCH)))
+	}
+	lockOpts, err := s.lockTimeoutOptions()
+	if err != nil {
+		return nil, err
+	}
+	appOpts = append(appOpts, lockOpts...)
+	connectionOpts, err := s.connectionsOptions()
+	if err != nil {
+		return nil, err
+	}
+	appOpts = append(appOpts, connectionOpts...)
+	progressOpts, err := s.progressOptions(logger)
+	if err != nil {
+		return nil, err
+	}
+	appOpts = append(appOpts, progressOpts...)
+	appOpts = append(appOpts, godl.WithLogger(logger))
+	if s.verbose {
+		appOpts = append(appOpts, godl.WithVerbose())
+	}
+	appOpts = append(appOpts, opts...)
+
+	a, err := godl.NewApplication(ctx, appOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing application: %w", err)
+	}
+	return a, nil
+}
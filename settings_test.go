@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testCasesSettings = []struct {
+	name     string
+	env      map[string]string
+	args     []string
+	expected settings
+	err      bool
+}{
+	{
+		name:     "defaults",
+		expected: settings{progress: progressAuto, connections: defaultConnections},
+	},
+	{
+		name: "environment",
+		env: map[string]string{
+			"GODL_VERBOSE":      "true",
+			"GODL_OFFLINE":      "1",
+			"GODL_CACHE_DIR":    "/var/cache/godl",
+			"GODL_OS":           "linux",
+			"GODL_ARCH":         "armv6l",
+			"GODL_MIRROR":       "https://mirror.example.com/dl/",
+			"GODL_PROGRESS":     progressLog,
+			"GODL_CONNECTIONS":  "8",
+			"GODL_LOCK_TIMEOUT": "30s",
+			"GODL_TIMEOUT":      "10m",
+		},
+		expected: settings{
+			verbose:     true,
+			offline:     true,
+			cacheDir:    "/var/cache/godl",
+			goos:        "linux",
+			goarch:      "armv6l",
+			mirror:      "https://mirror.example.com/dl/",
+			progress:    progressLog,
+			connections: "8",
+			lockTimeout: "30s",
+			timeout:     "10m",
+		},
+	},
+	{
+		name:     "flags-override-environment",
+		env:      map[string]string{"GODL_PROGRESS": progressLog, "GODL_CONNECTIONS": "8", "GODL_VERBOSE": "true"},
+		args:     []string{"-progress", progressOff, "-connections", "1", "-verbose=false"},
+		expected: settings{progress: progressOff, connections: "1"},
+	},
+	{
+		name:     "gosumdb-environment",
+		env:      map[string]string{"GOSUMDB": "off", "GOPRIVATE": "example.com/*"},
+		expected: settings{progress: progressAuto, connections: defaultConnections, gosumdb: "off", gonosumdb: "example.com/*"},
+	},
+	{
+		name:     "godl-gosumdb-first",
+		env:      map[string]string{"GOSUMDB": "off", "GODL_GOSUMDB": "sum.golang.org", "GONOSUMDB": "corp.example.com", "GOPRIVATE": "example.com/*"},
+		expected: settings{progress: progressAuto, connections: defaultConnections, gosumdb: "sum.golang.org", gonosumdb: "corp.example.com"},
+	},
+	{name: "unknown-progress", args: []string{"-progress", "fancy"}, err: true},
+	{name: "unknown-progress-environment", env: map[string]string{"GODL_PROGRESS": "fancy"}, err: true},
+	{name: "no-connections", args: []string{"-connections", "0"}, err: true},
+	{name: "negative-connections", args: []string{"-connections", "-2"}, err: true},
+	{name: "invalid-connections", args: []string{"-connections", "many"}, err: true},
+	{name: "no-connections-environment", env: map[string]string{"GODL_CONNECTIONS": "0"}, err: true},
+	{name: "invalid-lock-timeout", args: []string{"-lock-timeout", "soon"}, err: true},
+	{name: "negative-lock-timeout", args: []string{"-lock-timeout", "-1s"}, err: true},
+	{name: "invalid-timeout", env: map[string]string{"GODL_TIMEOUT": "10"}, err: true},
+}
+
+// unsetGodlEnv unsets the GODL_ and checksum database variables for the test
+func unsetGodlEnv(t *testing.T) {
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "GODL_") || name == "GOSUMDB" || name == "GONOSUMDB" || name == "GOPRIVATE" {
+			// restores the variable after the test
+			t.Setenv(name, "")
+			if err := os.Unsetenv(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestSettings(t *testing.T) {
+	for _, tc := range testCasesSettings {
+		t.Run(tc.name, func(t *testing.T) {
+			unsetGodlEnv(t)
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+			var s settings
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			s.register(fs)
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			err := s.validate()
+			if tc.err {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(tc.expected, s, cmp.AllowUnexported(settings{})); diff != "" {
+				t.Errorf("mismatch in expectation: \n\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sascha-andres/godl/godl"
+)
+
+// shells supported by env -shell, hooks are available for all but posix
+const (
+	shellBash  = "bash"
+	shellZsh   = "zsh"
+	shellFish  = "fish"
+	shellPosix = "posix"
+)
+
+// activeGorootVariable records the GOROOT godl added to PATH, so it can be replaced
+const activeGorootVariable = "GODL_ACTIVE_GOROOT"
+
+// checkShell returns an error if shell is not supported
+func checkShell(shell string) error {
+	switch shell {
+	case shellBash, shellZsh, shellFish, shellPosix:
+		return nil
+	}
+	return fmt.Errorf("unsupported shell %q, expected bash, zsh, fish or posix", shell)
+}
+
+// shellQuote quotes s for shell
+func shellQuote(shell, s string) string {
+	if shell == shellFish {
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// switchPath returns path without the bin directory of previous and with the bin directory of
+// goroot in front if not empty
+func switchPath(path, previous, goroot string) []string {
+	var result []string
+	if goroot != "" {
+		result = append(result, filepath.Join(goroot, "bin"))
+	}
+	for _, p := range filepath.SplitList(path) {
+		if p == "" || (previous != "" && filepath.Clean(p) == filepath.Join(previous, "bin")) || slices.Contains(result, p) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// writeShellEnv writes the commands activating goroot for shell, an empty goroot deactivates
+// the GOROOT activated before
+func writeShellEnv(w io.Writer, shell, goroot string) error {
+	path := switchPath(os.Getenv("PATH"), os.Getenv(activeGorootVariable), goroot)
+	var lines []string
+	switch {
+	case shell == shellFish && goroot == "":
+		lines = append(lines, "set -e GOROOT", "set -e "+activeGorootVariable)
+	case shell == shellFish:
+		lines = append(lines, "set -gx GOROOT "+shellQuote(shell, goroot), "set -gx "+activeGorootVariable+" "+shellQuote(shell, goroot))
+	case goroot == "":
+		lines = append(lines, "unset GOROOT "+activeGorootVariable)
+	default:
+		lines = append(lines, "export GOROOT="+shellQuote(shell, goroot), "export "+activeGorootVariable+"="+shellQuote(shell, goroot))
+	}
+	if shell == shellFish {
+		quoted := make([]string, len(path))
+		for i := range path {
+			quoted[i] = shellQuote(shell, path[i])
+		}
+		lines = append(lines, "set -gx PATH "+strings.Join(quoted, " "))
+	} else {
+		lines = append(lines, "export PATH="+shellQuote(shell, strings.Join(path, string(os.PathListSeparator))))
+	}
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// discoverGoroot returns the installation in destination matching the version required in the
+// working directory, empty if there is no requirement or it is not installed
+func discoverGoroot(destination string) (string, error) {
+	constraint, p, err := godl.DiscoverVersion(".")
+	if errors.Is(err, godl.ErrNoVersionFile) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	v, err := godl.ResolveInstalled(constraint, destination)
+	if errors.Is(err, godl.ErrNotInstalled) {
+		_, _ = fmt.Fprintf(os.Stderr, "godl: go %s required by %s is not installed in %s, run godl install\n", constraint, p, destination)
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(destination, v.String()), nil
+}
+
+// hookScripts are the prompt hooks for each shell, %[1]s is the quoted godl command
+var hookScripts = map[string]string{
+	shellBash: `_godl_hook() {
+  if [ "${_GODL_LAST_PWD:-}" != "$PWD" ]; then
+    _GODL_LAST_PWD="$PWD"
+    eval "$(%[1]s)"
+  fi
+}
+case ";${PROMPT_COMMAND:-};" in
+  *";_godl_hook;"*) ;;
+  *) PROMPT_COMMAND="_godl_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`,
+	shellZsh: `_godl_hook() {
+  eval "$(%[1]s)"
+}
+typeset -ag chpwd_functions
+if (( ! ${chpwd_functions[(I)_godl_hook]} )); then
+  chpwd_functions+=(_godl_hook)
+fi
+_godl_hook
+`,
+	shellFish: `function _godl_hook --on-variable PWD
+    %[1]s | source
+end
+_godl_hook
+`,
+}
+
+// runHook implements godl hook <shell>
+func runHook(args []string) error {
+	fs := flag.NewFlagSet("hook", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl hook [-destination dir] bash|zsh|fish\n\n"+
+			"Prints a hook activating the installed version required by .go-version, go.work or go.mod\n"+
+			"whenever the working directory changes, e.g. eval \"$(godl hook bash)\" in ~/.bashrc.\n\n")
+		fs.PrintDefaults()
+	}
+	destination, _ := destinationFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one shell")
+	}
+	if *destination == "" {
+		return errors.New("no destination provided")
+	}
+	shell := fs.Arg(0)
+	script, ok := hookScripts[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %q, expected bash, zsh or fish", shell)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	destinationPath, err := filepath.Abs(*destination)
+	if err != nil {
+		return err
+	}
+	command := strings.Join([]string{
+		shellQuote(shell, executable), "env", "-shell", shell, "-discover", "-destination", shellQuote(shell, destinationPath),
+	}, " ")
+	_, err = fmt.Printf(script, command)
+	return err
+}
@@ -0,0 +1,275 @@
+//go:build !windows
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testShellBinaries are the executables running the scripts of each shell
+var testShellBinaries = map[string]string{shellBash: "bash", shellZsh: "zsh", shellFish: "fish", shellPosix: "sh"}
+
+// runShell runs script using shell with environment env and returns its output, the test is
+// skipped if shell is not installed
+func runShell(t *testing.T, shell, script string, env ...string) string {
+	p, err := exec.LookPath(testShellBinaries[shell])
+	if err != nil {
+		t.Skipf("%s is not installed", testShellBinaries[shell])
+	}
+	cmd := exec.Command(p, "-c", script)
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("error running %s: %s\n%s", shell, err, stderr.String())
+	}
+	return string(out)
+}
+
+var testCasesShellQuote = []struct {
+	name     string
+	shell    string
+	value    string
+	expected string
+}{
+	{name: "bash-space", shell: shellBash, value: "/opt/my go", expected: `'/opt/my go'`},
+	{name: "bash-quotes", shell: shellBash, value: `/opt/it's "go"`, expected: `'/opt/it'\''s "go"'`},
+	{name: "bash-backslash", shell: shellBash, value: `/opt/go\bin`, expected: `'/opt/go\bin'`},
+	{name: "zsh-space", shell: shellZsh, value: "/opt/my go", expected: `'/opt/my go'`},
+	{name: "zsh-quotes", shell: shellZsh, value: `/opt/it's "go"`, expected: `'/opt/it'\''s "go"'`},
+	{name: "zsh-backslash", shell: shellZsh, value: `/opt/go\bin`, expected: `'/opt/go\bin'`},
+	{name: "fish-space", shell: shellFish, value: "/opt/my go", expected: `'/opt/my go'`},
+	{name: "fish-quotes", shell: shellFish, value: `/opt/it's "go"`, expected: `'/opt/it\'s "go"'`},
+	{name: "fish-backslash", shell: shellFish, value: `/opt/go\bin`, expected: `'/opt/go\\bin'`},
+	{name: "posix-space", shell: shellPosix, value: "/opt/my go", expected: `'/opt/my go'`},
+	{name: "posix-quotes", shell: shellPosix, value: `/opt/it's "go"`, expected: `'/opt/it'\''s "go"'`},
+	{name: "posix-backslash", shell: shellPosix, value: `/opt/go\bin`, expected: `'/opt/go\bin'`},
+}
+
+func TestShellQuote(t *testing.T) {
+	for _, tc := range testCasesShellQuote {
+		t.Run(tc.name, func(t *testing.T) {
+			quoted := shellQuote(tc.shell, tc.value)
+			if quoted != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, quoted)
+			}
+			if out := runShell(t, tc.shell, "printf '%s' "+quoted); out != tc.value {
+				t.Errorf("expected %s to read %q, got %q", tc.shell, tc.value, out)
+			}
+		})
+	}
+}
+
+var testCasesSwitchPath = []struct {
+	name     string
+	path     string
+	previous string
+	goroot   string
+	expected []string
+}{
+	{name: "activate", path: "/usr/local/bin:/usr/bin", goroot: "/opt/go/1.22.1", expected: []string{"/opt/go/1.22.1/bin", "/usr/local/bin", "/usr/bin"}},
+	{name: "replace", path: "/opt/go/1.21.5/bin:/usr/bin", previous: "/opt/go/1.21.5", goroot: "/opt/go/1.22.1", expected: []string{"/opt/go/1.22.1/bin", "/usr/bin"}},
+	{name: "replace-moved", path: "/usr/bin:/opt/go/1.21.5/bin/", previous: "/opt/go/1.21.5", goroot: "/opt/go/1.22.1", expected: []string{"/opt/go/1.22.1/bin", "/usr/bin"}},
+	{name: "same", path: "/opt/go/1.22.1/bin:/usr/bin", previous: "/opt/go/1.22.1", goroot: "/opt/go/1.22.1", expected: []string{"/opt/go/1.22.1/bin", "/usr/bin"}},
+	{name: "present", path: "/usr/bin:/opt/go/1.22.1/bin", goroot: "/opt/go/1.22.1", expected: []string{"/opt/go/1.22.1/bin", "/usr/bin"}},
+	{name: "deactivate", path: "/opt/go/1.21.5/bin:/usr/bin", previous: "/opt/go/1.21.5", expected: []string{"/usr/bin"}},
+	{name: "spaces", path: "/opt/my go/1.21.5/bin:/usr/bin", previous: "/opt/my go/1.21.5", goroot: "/opt/my go/1.22.1", expected: []string{"/opt/my go/1.22.1/bin", "/usr/bin"}},
+	{name: "empty-entries", path: "/usr/bin::/bin:", goroot: "/opt/go/1.22.1", expected: []string{"/opt/go/1.22.1/bin", "/usr/bin", "/bin"}},
+}
+
+func TestSwitchPath(t *testing.T) {
+	for _, tc := range testCasesSwitchPath {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.expected, switchPath(tc.path, tc.previous, tc.goroot)); diff != "" {
+				t.Errorf("mismatch in expectation: \n\n%s", diff)
+			}
+		})
+	}
+}
+
+// testGoroot is a GOROOT requiring quotes in every shell
+const testGoroot = `/opt/my go/it's/1.22.1`
+
+var testCasesWriteShellEnv = []struct {
+	name     string
+	shell    string
+	goroot   string
+	expected string
+}{
+	{
+		name:   "bash",
+		shell:  shellBash,
+		goroot: testGoroot,
+		expected: `export GOROOT='/opt/my go/it'\''s/1.22.1'
+export GODL_ACTIVE_GOROOT='/opt/my go/it'\''s/1.22.1'
+export PATH='/opt/my go/it'\''s/1.22.1/bin:/usr/local/bin:/usr/bin'
+`,
+	},
+	{
+		name:  "bash-deactivate",
+		shell: shellBash,
+		expected: `unset GOROOT GODL_ACTIVE_GOROOT
+export PATH='/usr/local/bin:/usr/bin'
+`,
+	},
+	{
+		name:   "zsh",
+		shell:  shellZsh,
+		goroot: testGoroot,
+		expected: `export GOROOT='/opt/my go/it'\''s/1.22.1'
+export GODL_ACTIVE_GOROOT='/opt/my go/it'\''s/1.22.1'
+export PATH='/opt/my go/it'\''s/1.22.1/bin:/usr/local/bin:/usr/bin'
+`,
+	},
+	{
+		name:  "zsh-deactivate",
+		shell: shellZsh,
+		expected: `unset GOROOT GODL_ACTIVE_GOROOT
+export PATH='/usr/local/bin:/usr/bin'
+`,
+	},
+	{
+		name:   "fish",
+		shell:  shellFish,
+		goroot: testGoroot,
+		expected: `set -gx GOROOT '/opt/my go/it\'s/1.22.1'
+set -gx GODL_ACTIVE_GOROOT '/opt/my go/it\'s/1.22.1'
+set -gx PATH '/opt/my go/it\'s/1.22.1/bin' '/usr/local/bin' '/usr/bin'
+`,
+	},
+	{
+		name:  "fish-deactivate",
+		shell: shellFish,
+		expected: `set -e GOROOT
+set -e GODL_ACTIVE_GOROOT
+set -gx PATH '/usr/local/bin' '/usr/bin'
+`,
+	},
+	{
+		name:   "posix",
+		shell:  shellPosix,
+		goroot: testGoroot,
+		expected: `export GOROOT='/opt/my go/it'\''s/1.22.1'
+export GODL_ACTIVE_GOROOT='/opt/my go/it'\''s/1.22.1'
+export PATH='/opt/my go/it'\''s/1.22.1/bin:/usr/local/bin:/usr/bin'
+`,
+	},
+	{
+		name:  "posix-deactivate",
+		shell: shellPosix,
+		expected: `unset GOROOT GODL_ACTIVE_GOROOT
+export PATH='/usr/local/bin:/usr/bin'
+`,
+	},
+}
+
+func TestWriteShellEnv(t *testing.T) {
+	for _, tc := range testCasesWriteShellEnv {
+		t.Run(tc.name, func(t *testing.T) {
+			// the version activated before is replaced
+			t.Setenv("PATH", "/opt/my go/it's/1.21.5/bin:/usr/local/bin:/usr/bin")
+			t.Setenv(activeGorootVariable, "/opt/my go/it's/1.21.5")
+			var b bytes.Buffer
+			if err := writeShellEnv(&b, tc.shell, tc.goroot); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if diff := cmp.Diff(tc.expected, b.String()); diff != "" {
+				t.Errorf("mismatch in expectation: \n\n%s", diff)
+			}
+		})
+	}
+}
+
+// shellEnv returns the value of name in the output of env
+func shellEnv(out, name string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if value, ok := strings.CutPrefix(line, name+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func TestShellSwitch(t *testing.T) {
+	dir := t.TempDir()
+	base := "/usr/local/bin:/usr/bin:/bin"
+	for _, shell := range []string{shellBash, shellZsh, shellFish, shellPosix} {
+		t.Run(shell, func(t *testing.T) {
+			path, active := base, ""
+			// every switch is evaluated by the shell, the next one starts from its environment
+			for _, goroot := range []string{filepath.Join(dir, "it's go", "1.21.5"), filepath.Join(dir, "it's go", "1.22.1"), ""} {
+				t.Setenv("PATH", path)
+				t.Setenv(activeGorootVariable, active)
+				var b bytes.Buffer
+				if err := writeShellEnv(&b, shell, goroot); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				env := []string{"PATH=" + path}
+				if active != "" {
+					env = append(env, activeGorootVariable+"="+active)
+				}
+				out := runShell(t, shell, b.String()+"env\n", env...)
+				path, active = shellEnv(out, "PATH"), shellEnv(out, activeGorootVariable)
+
+				expected := base
+				if goroot != "" {
+					expected = filepath.Join(goroot, "bin") + ":" + base
+				}
+				if path != expected {
+					t.Errorf("expected PATH %s after switching to %q, got %s", expected, goroot, path)
+				}
+				if active != goroot {
+					t.Errorf("expected %s %q, got %q", activeGorootVariable, goroot, active)
+				}
+				if shellEnv(out, "GOROOT") != goroot {
+					t.Errorf("expected GOROOT %q, got %q", goroot, shellEnv(out, "GOROOT"))
+				}
+			}
+		})
+	}
+}
+
+func TestHookScripts(t *testing.T) {
+	// a stand-in for godl env in a directory requiring quotes
+	dir := filepath.Join(t.TempDir(), `it's "godl"`)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	executable := filepath.Join(dir, "godl")
+	script := "#!/bin/sh\necho export GODL_TEST_HOOK=ran\n"
+	if err := os.WriteFile(executable, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		shell string
+		// check runs the hook installed twice and prints the variable set by it
+		check string
+	}{
+		{shell: shellBash, check: "_godl_hook\nprintf '%s %s' \"$GODL_TEST_HOOK\" \"$PROMPT_COMMAND\""},
+		{shell: shellZsh, check: "printf '%s %s' \"$GODL_TEST_HOOK\" \"${#chpwd_functions}\""},
+		{shell: shellFish, check: "printf '%s %s' \"$GODL_TEST_HOOK\" (count (functions --all | string match _godl_hook))"},
+	} {
+		t.Run(tc.shell, func(t *testing.T) {
+			hook := fmt.Sprintf(hookScripts[tc.shell], shellQuote(tc.shell, executable))
+			expected := map[string]string{shellBash: "ran _godl_hook", shellZsh: "ran 1", shellFish: "ran 1"}[tc.shell]
+			if out := runShell(t, tc.shell, hook+hook+tc.check, "PATH="+os.Getenv("PATH")); out != expected {
+				t.Errorf("expected %q, got %q", expected, out)
+			}
+		})
+	}
+
+	// posix shells have no hook
+	if err := runHook([]string{"-destination", t.TempDir(), shellPosix}); err == nil {
+		t.Error("expected error for posix hook")
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sascha-andres/godl/godl"
+)
+
+// subcommands are dispatched before the flags of the classic interface are parsed
+var subcommands = map[string]func(args []string) error{
+	"install":   runInstall,
+	"use":       runUse,
+	"ls-remote": runLsRemote,
+	"ls":        runList,
+	"list":      runList,
+	"rm":        runRemove,
+	"remove":    runRemove,
+	"prune":     runPrune,
+	"env":       runEnv,
+	"hook":      runHook,
+	"cache":     runCache,
+	"serve":     runServe,
+	"help":      runHelp,
+}
+
+// dispatch runs the subcommand named by the first argument, handled is false if
+// there is no such subcommand
+func dispatch(args []string) (handled bool, err error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	run, ok := subcommands[args[0]]
+	if !ok {
+		return false, nil
+	}
+	err = run(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return true, nil
+	}
+	return true, err
+}
+
+// envDefault returns the GODL_ environment variable for name or def if not set
+func envDefault(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+// formatBytes returns a human readable representation of size
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// cacheDirFlag registers -cache-dir on fs and returns a pointer to its value
+func cacheDirFlag(fs *flag.FlagSet) *string {
+	def, err := godl.DefaultCacheDir()
+	if err != nil {
+		def = ""
+	}
+	return fs.String("cache-dir", envDefault("GODL_CACHE_DIR", def), "directory downloaded archives are cached in")
+}
+
+// destinationFlags registers -destination and -link-name on fs and returns pointers to their values
+func destinationFlags(fs *flag.FlagSet) (destination, linkName *string) {
+	destination = fs.String("destination", envDefault("GODL_DESTINATION", ""), "directory go versions are installed in")
+	linkName = fs.String("link-name", envDefault("GODL_LINK_NAME", "current"), "name (path) of symlink")
+	return destination, linkName
+}
+
+// runList implements godl list
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl list [-destination dir] [-link-name name] [-output format]\n\n")
+		fs.PrintDefaults()
+	}
+	destination, linkName := destinationFlags(fs)
+	output := outputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *destination == "" {
+		return errors.New("no destination provided")
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	installations, err := godl.ListInstalled(*destination, *linkName)
+	if err != nil {
+		return err
+	}
+	return printInstallations(os.Stdout, *output, installations)
+}
+
+// runRemove implements godl remove <version>
+func runRemove(args []string) error {
+	fs := flag.NewFlagSet("remove", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl remove [-destination dir] [-link-name name] [-force] version\n\n")
+		fs.PrintDefaults()
+	}
+	destination, linkName := destinationFlags(fs)
+	force := fs.Bool("force", false, "remove the version even if it is linked, the link is removed as well")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one version")
+	}
+	if *destination == "" {
+		return errors.New("no destination provided")
+	}
+	installation, err := godl.RemoveInstalled(fs.Arg(0), *destination, *linkName, *force)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("removed %s (%s)\n", installation.Path, formatBytes(installation.Size))
+	return nil
+}
+
+// runPrune implements godl prune
+func runPrune(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl prune [-destination dir] [-link-name name] [-keep n] [-older-than duration]\n\n")
+		fs.PrintDefaults()
+	}
+	destination, linkName := destinationFlags(fs)
+	keep := fs.Int("keep", 0, "keep the newest n versions")
+	olderThan := fs.Duration("older-than", 0, "only remove versions installed longer ago than this (e.g. 720h)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *destination == "" {
+		return errors.New("no destination provided")
+	}
+	if *keep <= 0 && *olderThan <= 0 {
+		fs.Usage()
+		return errors.New("at least one of -keep or -older-than is required")
+	}
+	removed, err := godl.PruneInstalled(*destination, *linkName, *keep, *olderThan)
+	var total int64
+	for _, installation := range removed {
+		total += installation.Size
+		fmt.Printf("removed %s (%s)\n", installation.Path, formatBytes(installation.Size))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("reclaimed %s\n", formatBytes(total))
+	return nil
+}
+
+// runCache implements godl cache list|clean|size
+func runCache(args []string) error {
+	fs := flag.NewFlagSet("cache", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl cache [-cache-dir dir] [-output format] [-lock-timeout duration] list|clean|size\n\n")
+		fs.PrintDefaults()
+	}
+	var s settings
+	cacheDir := cacheDirFlag(fs)
+	output := outputFlag(fs)
+	s.registerLockTimeout(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one of list, clean or size")
+	}
+	if *cacheDir == "" {
+		return errors.New("no cache directory provided")
+	}
+	c := godl.NewCache(*cacheDir)
+
+	switch fs.Arg(0) {
+	case "list":
+		entries, err := c.List()
+		if err != nil {
+			return err
+		}
+		return printCacheEntries(os.Stdout, *output, entries)
+	case "clean":
+		lockTimeout, err := s.lockTimeoutDuration()
+		if err != nil {
+			return err
+		}
+		ctx, cancel, err := s.context()
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return c.Clean(ctx, lockTimeout)
+	case "size":
+		size, err := c.Size()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\t%s\n", formatBytes(size), c.Dir())
+		return nil
+	}
+	fs.Usage()
+	return fmt.Errorf("unknown cache command %q", fs.Arg(0))
+}
+
+// runServe implements godl serve, serving the archive cache as a release mirror below /dl/
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "usage: godl serve [-cache-dir dir] [-listen address]\n\n"+
+			"Serves the cached archives as a mirror at http://<address>/dl/, usable with -mirror.\n\n")
+		fs.PrintDefaults()
+	}
+	var s settings
+	cacheDir := cacheDirFlag(fs)
+	listen := fs.String("listen", envDefault("GODL_LISTEN", "localhost:8080"), "address to listen on, e.g. :8080 for all interfaces")
+	fs.BoolVar(&s.verbose, "verbose", envBool("GODL_VERBOSE"), "log every request")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+	if *cacheDir == "" {
+		return errors.New("no cache directory provided")
+	}
+
+	ctx, cancel, err := s.context()
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	logger := s.newLogger()
+	mux := http.NewServeMux()
+	mux.Handle("/dl/", http.StripPrefix("/dl", godl.NewMirrorHandler(godl.NewCache(*cacheDir), logger)))
+	srv := &http.Server{Addr: *listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	logger.Info("serving release mirror", "url", "http://"+*listen+"/dl/", "cache", *cacheDir)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}